@@ -1,10 +1,12 @@
 package pa
 
 import (
-	"fmt"
+	"context"
+	"go/token"
 	"go/types"
 	"io"
 	"strings"
+	"time"
 
 	"golang.org/x/tools/go/callgraph"
 	"golang.org/x/tools/go/ssa"
@@ -14,39 +16,201 @@ import (
 type analysis struct {
 	prog            *ssa.Program    // the program being analyzed
 	entryfuns       []*ssa.Function // entry points, including main function and exported functions
-	log             io.Writer       // log stream; nil to disable
+	opts            *Options        // analysis options
+	ctx             context.Context // cancels the analysis
+	selector        ContextSelector // chooses the contexts of callees
+	tracer          Tracer          // receives the events; nil to disable
 	panicNode       nodeid
 	nodes           []*node // indexed by nodeid
 	flattenBuf      map[types.Type][]*subEleInfo
 	globalval       map[ssa.Value]nodeid // node for each global ssa.Value
 	globalobj       map[ssa.Value]nodeid
-	csfuncobj       map[ssa.Value]map[context]nodeid
-	localval        map[ssa.Value]nodeid // node for each local ssa.Value
+	csfuncobj       map[ssa.Value]map[Context]nodeid
+	contexts        map[ctxstringKey]*ctxstring // interned contexts
+	localval        map[ssa.Value]nodeid        // node for each local ssa.Value
 	localobj        map[ssa.Value]nodeid
-	worklist        nodeset // solver's worklist
+	heapobj         map[ssa.Value]map[Context]nodeid // objects shared by clones, if HeapK != 0
+	worklist        worklist                         // solver's worklist, see Options.Worklist
+	flowVersion     int                              // incremented as the flow edges change
 	reachable_queue []*funcnode
+	generating      bool            // whether reachable_queue is being drained
+	curfn           *ssa.Function   // the function being generated, for errors
+	curinstr        ssa.Instruction // the instruction being generated, for errors
 	deltaSpace      []int
 	globalflushbuf  nodeset //clear global node prevptr on demand
 	flushSpace      []int
 
+	// reflection
+	reflectValue    types.Type        // reflect.Value, if the program has reflect
+	reflectRtypePtr *types.Pointer    // *reflect.rtype
+	rtypes          typeutil.Map      // canonical rtype object of each type
+	boundrecv       map[nodeid]nodeid // receiver of each bound method object
+
+	// body-less functions
+	bodyless     []*ssa.Function // in discovery order
+	bodylessSeen map[*ssa.Function]bool
+	conservative []*conservativeSink
+
+	// CHA fallback
+	dynsites []*dynSite // pending dynamic call sites
+	cha      *chaTable
+
+	recvsites []*receiverRule // pending receiver-resolved calls, see resolveEmptyReceivers
+
+	diagnostics    []Diagnostic // in discovery order
+	diagnosticSeen map[diagnosticKey]bool
+
+	// provenance, see Options.Provenance
+	provenance map[provKey]nodeid // the node each fact of a pts was first copied from
+	provSpace  []int
+	edgeCauses map[csEdgeKey]edgeCause
+	origins    map[nodeid]interface{} // the ssa.Value, or other origin, of each node
+
+	// cycle elimination
+	lcdSeen  map[lcdEdge]bool // the edges that triggered a cycle detection
+	lcdQueue []nodeid         // the nodes to search cycles from
+	lcdNext  int              // the Stats.Propagations of the next search
+	scc      sccSearch
+
+	ruleFires []*ruleFire // the rules to fire, see merge and addRule
+
+	ptsTable *ptsTable // the interned points-to sets, if Options.SharedPointsToSets
+
+	stats      Stats
+	iterations int    // solver iterations
+	incomplete string // why the analysis was stopped, if it was
+
 	// result
-	callgraph map[*ssa.Function]map[ssa.CallInstruction]map[*ssa.Function]bool // a temp callgraph to efficiently reduce possible redundant edges
-	CallGraph *callgraph.Graph                                                 // discovered call graph
+	root       *funcnode                                                        // the synthetic root of the call graph
+	callgraph  map[*ssa.Function]map[ssa.CallInstruction]map[*ssa.Function]bool // a temp callgraph to efficiently reduce possible redundant edges; false for approximate edges
+	csedges    []csEdgeKey                                                      // context-sensitive call edges, in discovery order
+	csedgeSeen map[csEdgeKey]bool
+	csapprox   map[csEdgeKey]bool       // the edges resolved by CHA only
+	CallGraph  *callgraph.Graph         // discovered call graph
+	approx     map[*callgraph.Edge]bool // the edges of CallGraph resolved by CHA only
 }
 
-func Analyze(prog_ *ssa.Program, log_ io.Writer, paks []*ssa.Package, entry_funcs []*ssa.Function) (result *callgraph.Graph, err error) {
+func Analyze(prog_ *ssa.Program, log_ io.Writer, paks []*ssa.Package, entry_funcs []*ssa.Function, opts *Options) (result *Result, err error) {
+	return AnalyzeContext(context.Background(), prog_, log_, paks, entry_funcs, opts)
+}
 
+// AnalyzeContext is like Analyze, but stops when ctx is done,
+// returning a partial result flagged as Incomplete.
+func AnalyzeContext(ctx context.Context, prog_ *ssa.Program, log_ io.Writer, paks []*ssa.Package, entry_funcs []*ssa.Function, opts *Options) (result *Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*UnsupportedError)
+			if !ok {
+				panic(r) // a bug, see internalError
+			}
+			result, err = nil, e
+		}
+	}()
+
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+
+	var unmatchedModels []string
+	if len(opts.ModelFiles) > 0 {
+		summaries, unmatched, err := loadModels(prog_, opts.ModelFiles...)
+		if err != nil {
+			return nil, err
+		}
+		for fn, s := range opts.Summaries {
+			summaries[fn] = s
+		}
+		merged := *opts
+		merged.Summaries = summaries
+		merged.ModelFiles = nil
+		opts = &merged
+		unmatchedModels = unmatched
+	}
+
+	var metrics map[*ssa.Function]*FuncMetrics
+	var introspectionTime time.Duration
+	var preIncomplete string
+	if opts.Introspection != nil {
+		// Pre-analysis: decide which functions are worth cloning.
+		start := time.Now()
+		metrics, opts, preIncomplete = introspect(ctx, prog_, paks, entry_funcs, opts)
+		introspectionTime = time.Since(start)
+	}
+
+	tracer := opts.Tracer
+	if tracer == nil && log_ != nil {
+		tracer = &textTracer{log_}
+	}
+	a := analyze(ctx, prog_, tracer, paks, entry_funcs, opts)
+	a.stats.IntrospectionTime = introspectionTime
+	if preIncomplete != "" {
+		reason := "introspection: " + preIncomplete
+		if a.incomplete != "" {
+			reason += "; " + a.incomplete
+		}
+		a.incomplete = reason
+	}
+	for _, name := range unmatchedModels {
+		a.diagnose(UnmatchedModel, token.NoPos, nil, "no package of the program has %s", name)
+	}
+	return &Result{
+		CallGraph:   a.CallGraph,
+		CSCallGraph: a.csCallGraph(),
+		FuncMetrics: metrics,
+		Bodyless:    a.bodyless,
+		Diagnostics: a.diagnostics,
+		Approximate: a.approx,
+		Stats:       &a.stats,
+
+		Incomplete:       a.incomplete != "",
+		IncompleteReason: a.incomplete,
+
+		a: a,
+	}, nil
+}
+
+// analyze runs the analysis, up to the construction of the call graph.
+func analyze(ctx context.Context, prog_ *ssa.Program, tracer Tracer, paks []*ssa.Package, entry_funcs []*ssa.Function, opts *Options) *analysis {
 	a := &analysis{
-		log:        log_,
-		entryfuns:  entry_funcs,
-		prog:       prog_,
-		globalval:  make(map[ssa.Value]nodeid),
-		globalobj:  make(map[ssa.Value]nodeid),
-		flattenBuf: make(map[types.Type][]*subEleInfo),
-		csfuncobj:  make(map[ssa.Value]map[context]nodeid),
-		deltaSpace: make([]int, 0, 100),
-		flushSpace: make([]int, 0, 100),
-		nodes:      make([]*node, 0),
+		ctx:            ctx,
+		tracer:         tracer,
+		opts:           opts,
+		entryfuns:      entry_funcs,
+		prog:           prog_,
+		globalval:      make(map[ssa.Value]nodeid),
+		globalobj:      make(map[ssa.Value]nodeid),
+		flattenBuf:     make(map[types.Type][]*subEleInfo),
+		csfuncobj:      make(map[ssa.Value]map[Context]nodeid),
+		heapobj:        make(map[ssa.Value]map[Context]nodeid),
+		boundrecv:      make(map[nodeid]nodeid),
+		bodylessSeen:   make(map[*ssa.Function]bool),
+		diagnosticSeen: make(map[diagnosticKey]bool),
+		csedgeSeen:     make(map[csEdgeKey]bool),
+		csapprox:       make(map[csEdgeKey]bool),
+		approx:         make(map[*callgraph.Edge]bool),
+		provenance:     make(map[provKey]nodeid),
+		edgeCauses:     make(map[csEdgeKey]edgeCause),
+		origins:        make(map[nodeid]interface{}),
+		lcdSeen:        make(map[lcdEdge]bool),
+		contexts:       make(map[ctxstringKey]*ctxstring),
+		deltaSpace:     make([]int, 0, 100),
+		flushSpace:     make([]int, 0, 100),
+		nodes:          make([]*node, 0),
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			panic(a.internalError(r))
+		}
+	}()
+
+	a.worklist = a.newWorklist()
+	if opts.SharedPointsToSets {
+		a.ptsTable = newPtsTable()
+	}
+
+	a.selector = opts.ContextSelector
+	if a.selector == nil {
+		a.selector = CallSiteSensitive{K: opts.K}
 	}
 
 	// Pass ssa.package is also ok.
@@ -56,18 +220,8 @@ func Analyze(prog_ *ssa.Program, log_ io.Writer, paks []*ssa.Package, entry_func
 	}
 
 	if reflect := a.prog.ImportedPackage("reflect"); reflect != nil {
-		if a.log != nil {
-			fmt.Fprintln(a.log, "reflect not support")
-		}
-	}
-	if runtime := a.prog.ImportedPackage("runtime"); runtime != nil {
-		if a.log != nil {
-			fmt.Fprintln(a.log, "runtime not support")
-		}
-	}
-
-	if a.log != nil {
-		fmt.Fprintln(a.log, "----------- Starting analysis -----------")
+		a.reflectValue = reflect.Pkg.Scope().Lookup("Value").Type()
+		a.reflectRtypePtr = types.NewPointer(reflect.Pkg.Scope().Lookup("rtype").Type())
 	}
 
 	a.solve()
@@ -75,13 +229,17 @@ func Analyze(prog_ *ssa.Program, log_ io.Writer, paks []*ssa.Package, entry_func
 	// contruct final call graph
 	for f1, call := range a.callgraph {
 		for callinstr, f2set := range call {
-			for f2 := range f2set {
-				callgraph.AddEdge(a.CallGraph.CreateNode(f1), callinstr, a.CallGraph.CreateNode(f2))
+			for f2, precise := range f2set {
+				n1 := a.CallGraph.CreateNode(f1)
+				callgraph.AddEdge(n1, callinstr, a.CallGraph.CreateNode(f2))
+				if !precise {
+					a.approx[n1.Out[len(n1.Out)-1]] = true
+				}
 			}
 		}
 	}
 
-	return a.CallGraph, nil
+	return a
 }
 
 func (a *analysis) entryPoints(topPackages []*ssa.Package) []*ssa.Function {
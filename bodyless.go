@@ -0,0 +1,106 @@
+package pa
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Body-less functions, i.e. assembly, //go:linkname and cgo stubs, are
+// reported in Result.Bodyless. Unless Options.ConservativeBodyless is set,
+// they have no effect.
+//
+// In the conservative mode, each pointer-like node of their results may
+// point to any object of a compatible type, and their func-typed params
+// are called with arguments that may likewise point to any compatible object.
+// The nodes so treated are conservative sinks, filled by the solver
+// whenever the worklist is empty, until no object is left to add.
+
+// A conservativeSink is a node that may point to any object compatible with typ.
+type conservativeSink struct {
+	id   nodeid
+	typ  types.Type
+	seen nodeid // the objects before seen are already added
+}
+
+// genBodyless records the body-less function of fc and, in the
+// conservative mode, generates its type-based summary.
+func (a *analysis) genBodyless(fc *funcnode) {
+	fn := fc.fn
+	if !a.bodylessSeen[fn] {
+		a.bodylessSeen[fn] = true
+		a.bodyless = append(a.bodyless, fn)
+	}
+	if !a.opts.ConservativeBodyless {
+		a.diagnose(BodylessFunction, fn.Pos(), fn, "function %s has no body", fn)
+		return
+	}
+
+	sig := fn.Signature
+	a.addConservativeSinks(a.funcResults(fc.obj), sig.Results())
+
+	// Call the func-typed params.
+	params := a.funcParams(fc.obj)
+	if recv := sig.Recv(); recv != nil {
+		params += nodeid(a.sizeof(recv.Type()))
+	}
+	for i, n := 0, sig.Params().Len(); i < n; i++ {
+		T := sig.Params().At(i).Type()
+		if fsig, ok := T.Underlying().(*types.Signature); ok {
+			block := a.nextNode()
+			a.addNodes(fsig.Params(), "bodyless.params")
+			a.addNodes(fsig.Results(), "bodyless.results")
+			a.addConservativeSinks(block, fsig.Params())
+			a.addRule(params, &fpRule{caller: fc, params: block, value: params})
+		}
+		params += nodeid(a.sizeof(T))
+	}
+}
+
+// addConservativeSinks makes the pointer-like nodes of type T starting at id conservative sinks.
+func (a *analysis) addConservativeSinks(id nodeid, T types.Type) {
+	for i, fi := range a.flatten(T) {
+		switch fi.typ.Underlying().(type) {
+		case *types.Pointer, *types.Slice, *types.Interface, *types.Signature:
+			a.conservative = append(a.conservative, &conservativeSink{id: id + nodeid(i), typ: fi.typ})
+		}
+	}
+}
+
+// flushConservative adds to the conservative sinks the compatible objects
+// created since the last flush, and reports whether any points-to set changed.
+func (a *analysis) flushConservative() bool {
+	changed := false
+	for _, s := range a.conservative {
+		for id := s.seen; id < a.nextNode(); id++ {
+			if a.nodes[id].obj != nil && a.conservativeMatch(s.typ, id) {
+				if a.nodes[s.id].pts.add(id) {
+					a.addWork(s.id)
+					changed = true
+				}
+			}
+		}
+		s.seen = a.nextNode()
+	}
+	return changed
+}
+
+// conservativeMatch reports whether a node of type T may point to the object obj.
+// Maps and channels are not supported.
+func (a *analysis) conservativeMatch(T types.Type, obj nodeid) bool {
+	n := a.nodes[obj]
+	switch t := T.Underlying().(type) {
+	case *types.Pointer:
+		return n.obj.tags == 0 && types.Identical(n.typ, a.flatten(t.Elem())[0].typ)
+	case *types.Slice:
+		return n.obj.tags == 0 && types.Identical(n.typ, a.flatten(sliceToArray(t))[0].typ)
+	case *types.Interface:
+		return n.obj.tags&otTagged != 0 && types.AssignableTo(n.typ, T)
+	case *types.Signature:
+		// Function values, not the clones of functions.
+		fn, ok := n.obj.data.(*ssa.Function)
+		return ok && n.obj.tags&otFunction != 0 && n.obj.funcn == nil &&
+			fn.Signature.Recv() == nil && types.Identical(fn.Signature, t)
+	}
+	return false
+}
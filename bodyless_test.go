@@ -0,0 +1,68 @@
+package pa
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const bodylessSample = `package main
+
+type T struct{ p *int }
+
+func ext() *int
+func each(f func(*T))
+
+var sink *T
+
+func use(p *int) {}
+
+func visit(t *T) {}
+
+func main() {
+	t := &T{p: new(int)}
+	sink = &T{}
+	use(ext())
+	each(visit)
+	_ = t
+}
+`
+
+func TestBodyless(t *testing.T) {
+	pkg := buildSample(t, bodylessSample)
+	for _, test := range []struct {
+		conservative bool
+		use, visit   []string
+	}{
+		{false, []string{}, []string{}},
+		{true, []string{"main.main:t2"}, []string{"main.main:t0", "main.main:t3"}},
+	} {
+		res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{ConservativeBodyless: test.conservative})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var bodyless []string
+		for _, fn := range res.Bodyless {
+			bodyless = append(bodyless, fn.String())
+		}
+		if want := []string{"main.ext", "main.each"}; !reflect.DeepEqual(bodyless, want) {
+			t.Errorf("ConservativeBodyless %v: got bodyless functions %v, want %v", test.conservative, bodyless, want)
+		}
+		diagnosed := 0
+		for _, d := range res.Diagnostics {
+			if d.Kind == BodylessFunction {
+				diagnosed++
+			}
+		}
+		if want := map[bool]int{false: 2, true: 0}[test.conservative]; diagnosed != want {
+			t.Errorf("ConservativeBodyless %v: %d bodyless functions diagnosed, want %d", test.conservative, diagnosed, want)
+		}
+		if got := labels(res, sampleParam(t, pkg, "main.use", "p")); !reflect.DeepEqual(got, test.use) {
+			t.Errorf("ConservativeBodyless %v: the result of ext points to %v, want %v", test.conservative, got, test.use)
+		}
+		if got := labels(res, sampleParam(t, pkg, "main.visit", "t")); !reflect.DeepEqual(got, test.visit) {
+			t.Errorf("ConservativeBodyless %v: the param of visit points to %v, want %v", test.conservative, got, test.visit)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package pa
+
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// ctxPollInterval is the number of solver iterations between two checks
+// of the cancellation of the context.
+const ctxPollInterval = 1024
+
+// budgetExceeded reports whether the analysis must stop, because its
+// context is done or one of the budgets of Options is exceeded.
+// The reason is recorded in a.incomplete, and the analysis stays stopped.
+// pollCtx requests a check of the context.
+func (a *analysis) budgetExceeded(pollCtx bool) bool {
+	if a.incomplete != "" {
+		return true
+	}
+	switch {
+	case a.opts.MaxNodes > 0 && len(a.nodes) > a.opts.MaxNodes:
+		a.incomplete = fmt.Sprintf("node budget of %d exceeded", a.opts.MaxNodes)
+	case a.opts.MaxIterations > 0 && a.iterations > a.opts.MaxIterations:
+		a.incomplete = fmt.Sprintf("iteration budget of %d exceeded", a.opts.MaxIterations)
+	case pollCtx && a.ctx.Err() != nil:
+		a.incomplete = a.ctx.Err().Error()
+	}
+	return a.incomplete != ""
+}
+
+// limitContext returns the context of a new clone of fn: ctx,
+// or the empty context once fn has Options.MaxContextsPerFunc clones.
+// The clone in the empty context is shared by the invocations beyond the budget,
+// and counts toward it: a slot is kept for it until it is created.
+func (a *analysis) limitContext(fn *ssa.Function, ctx Context) Context {
+	max := a.opts.MaxContextsPerFunc
+	if max <= 0 || ctx.Len() == 0 {
+		return ctx
+	}
+	if _, ok := a.csfuncobj[fn][NewContext()]; !ok {
+		max--
+	}
+	if len(a.csfuncobj[fn]) >= max {
+		return NewContext()
+	}
+	return ctx
+}
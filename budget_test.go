@@ -0,0 +1,81 @@
+package pa
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const budgetSample = `package main
+
+func f(p *int) {}
+
+func main() {
+	f(new(int))
+	f(new(int))
+	f(new(int))
+	f(new(int))
+	f(new(int))
+}
+`
+
+func TestMaxContextsPerFunc(t *testing.T) {
+	pkg := buildSample(t, budgetSample)
+	p := sampleParam(t, pkg, "main.f", "p")
+	for _, test := range []struct {
+		max, clones int
+		empty       bool // whether a clone is in the empty context
+	}{
+		{0, 5, false},
+		{1, 1, true},
+		{2, 2, true},
+		{3, 3, true},
+		{5, 5, true},
+		{6, 5, false},
+	} {
+		res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{K: 1, MaxContextsPerFunc: test.max})
+		if err != nil {
+			t.Fatal(err)
+		}
+		byCtx := res.PointsToByContext(p)
+		empty := false
+		for _, c := range byCtx {
+			if c.Context.Len() == 0 {
+				empty = true
+			}
+		}
+		if len(byCtx) != test.clones || empty != test.empty {
+			t.Errorf("MaxContextsPerFunc %d: got %d clones, empty context %v; want %d, %v", test.max, len(byCtx), empty, test.clones, test.empty)
+		}
+		if got := len(res.PointsTo(p)); got != 5 {
+			t.Errorf("MaxContextsPerFunc %d: p points to %d objects, want 5", test.max, got)
+		}
+	}
+}
+
+func TestBudgets(t *testing.T) {
+	pkg := buildSample(t, parallelSamples["synthetic"])
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, test := range []struct {
+		ctx    context.Context
+		opts   *Options
+		reason string // "" if complete
+	}{
+		{context.Background(), &Options{K: 1}, ""},
+		{context.Background(), &Options{K: 1, MaxNodes: 100}, "node budget of 100 exceeded"},
+		{context.Background(), &Options{K: 1, MaxIterations: 10}, "iteration budget of 10 exceeded"},
+		{canceled, &Options{K: 1}, "context canceled"},
+		{context.Background(), &Options{K: 1, MaxIterations: 10, Introspection: Introspective(1000, 1000)}, "introspection: iteration budget of 10 exceeded"},
+	} {
+		res, err := AnalyzeContext(test.ctx, pkg.Prog, nil, []*ssa.Package{pkg}, nil, test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Incomplete != (test.reason != "") || !strings.HasPrefix(res.IncompleteReason, test.reason) {
+			t.Errorf("%+v: got Incomplete %v (%q), want %q", *test.opts, res.Incomplete, res.IncompleteReason, test.reason)
+		}
+	}
+}
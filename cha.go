@@ -0,0 +1,151 @@
+package pa
+
+import (
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// With Options.CHAFallback, the dynamic call sites whose callee value
+// still points to nothing at the fixpoint, e.g. because it comes from an
+// unmodeled function, are resolved by class hierarchy analysis: a call of a
+// func value may call any function of the program with the same signature,
+// an invocation of an interface method any method of a type implementing
+// the interface. The callees are made reachable, with the arguments of the
+// call flowing to their params, and the call edges are marked approximate.
+
+// A dynSite is a dynamic call site, pending the check of its callee value.
+type dynSite struct {
+	caller *funcnode
+	site   ssa.CallInstruction
+	value  nodeid // the callee value, func or interface
+	params nodeid // the start of the params/results block
+}
+
+// chaTable holds the candidate callees of CHA, built on first use.
+type chaTable struct {
+	funcsBySig    typeutil.Map // []*ssa.Function, the functions of each signature
+	methodsByName map[string][]*ssa.Function
+	methods       map[chaMethod][]*ssa.Function // memo of the implementations of I.m
+}
+
+type chaMethod struct {
+	iface *types.Interface
+	id    string
+}
+
+// addDynSite records a dynamic call site for the CHA fallback.
+func (a *analysis) addDynSite(caller *funcnode, site ssa.CallInstruction, value, params nodeid) {
+	if a.opts.CHAFallback {
+		a.dynsites = append(a.dynsites, &dynSite{caller, site, value, params})
+	}
+}
+
+// resolveByCHA resolves by CHA the pending dynamic call sites whose callee
+// value points to nothing, and reports whether any was.
+// The other sites are resolved by the solver.
+func (a *analysis) resolveByCHA() bool {
+	resolved := false
+	for len(a.dynsites) > 0 {
+		s := a.dynsites[0]
+		a.dynsites = a.dynsites[1:]
+		if !a.nodes[s.value].pts.set().IsEmpty() {
+			continue
+		}
+		resolved = true
+		for _, fn := range a.chaCallees(s.site) {
+			a.callByCHA(s, fn)
+		}
+	}
+	return resolved
+}
+
+// callByCHA connects the call of s to the clone of fn selected for it.
+// The receiver of a method, unknown, points to nothing.
+func (a *analysis) callByCHA(s *dynSite, fn *ssa.Function) {
+	obj := a.funcObject(&Invocation{
+		Caller:  s.caller.fn,
+		Context: s.caller.func_context,
+		Site:    s.site,
+		Callee:  fn,
+	})
+
+	a.addApproxCallGraphEdge(s.caller, s.site, a.nodes[obj].obj.funcn)
+	a.recordEdge(s.caller, s.site, a.nodes[obj].obj.funcn, edgeCause{node: s.value, cha: true})
+
+	sig := fn.Signature
+	src := s.params
+	dst := a.funcParams(obj)
+	if recv := sig.Recv(); recv != nil {
+		dst += nodeid(a.sizeof(recv.Type()))
+	}
+
+	// Copy caller's argument block to method formal parameters.
+	paramsSize := a.sizeof(sig.Params())
+	a.auxaddflowN(dst, src, paramsSize)
+	src += nodeid(paramsSize)
+	dst += nodeid(paramsSize)
+
+	// Copy method results to caller's result block.
+	resultsSize := a.sizeof(sig.Results())
+	a.auxaddflowN(src, dst, resultsSize)
+}
+
+// chaCallees returns the callees of the dynamic call site by CHA.
+func (a *analysis) chaCallees(site ssa.CallInstruction) []*ssa.Function {
+	if a.cha == nil {
+		a.cha = newCHATable(a.prog)
+	}
+	call := site.Common()
+	if !call.IsInvoke() {
+		fns, _ := a.cha.funcsBySig.At(call.Signature()).([]*ssa.Function)
+		return fns
+	}
+
+	I := call.Value.Type().Underlying().(*types.Interface)
+	key := chaMethod{I, call.Method.Id()}
+	methods, ok := a.cha.methods[key]
+	if !ok {
+		for _, f := range a.cha.methodsByName[call.Method.Name()] {
+			if types.Implements(f.Signature.Recv().Type(), I) {
+				methods = append(methods, f)
+			}
+		}
+		a.cha.methods[key] = methods
+	}
+	return methods
+}
+
+func newCHATable(prog *ssa.Program) *chaTable {
+	t := &chaTable{
+		methodsByName: make(map[string][]*ssa.Function),
+		methods:       make(map[chaMethod][]*ssa.Function),
+	}
+	// In a deterministic order, for the order of the callees.
+	var fns []*ssa.Function
+	for f := range ssautil.AllFunctions(prog) {
+		// Generic function bodies are not analyzed, their instances are.
+		if f.TypeParams().Len() > 0 && len(f.TypeArgs()) == 0 {
+			continue
+		}
+		fns = append(fns, f)
+	}
+	sort.Slice(fns, func(i, j int) bool { return fns[i].String() < fns[j].String() })
+
+	for _, f := range fns {
+		if f.Signature.Recv() == nil {
+			// Package initializers can never be address-taken.
+			if f.Name() == "init" && f.Synthetic == "package initializer" {
+				continue
+			}
+			funcs, _ := t.funcsBySig.At(f.Signature).([]*ssa.Function)
+			t.funcsBySig.Set(f.Signature, append(funcs, f))
+		} else {
+			t.methodsByName[f.Name()] = append(t.methodsByName[f.Name()], f)
+		}
+	}
+	return t
+}
@@ -0,0 +1,68 @@
+package pa
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+const chaSample = `package main
+
+type I interface{ M(p *int) }
+
+type A struct{}
+type B struct{}
+
+func (A) M(p *int)  {}
+func (*B) M(p *int) {}
+
+func f1(p *int) {}
+func f2(p *int) {}
+func g(s string) {}
+
+func getF() func(*int)
+func getI() I
+
+func main() {
+	x := new(int)
+	getF()(x)
+	getI().M(x)
+}
+`
+
+func TestCHAFallback(t *testing.T) {
+	pkg := buildSample(t, chaSample)
+	for _, cha := range []bool{false, true} {
+		res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{CHAFallback: cha})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var edges []string
+		callgraph.GraphVisitEdges(res.CallGraph, func(e *callgraph.Edge) error {
+			if e.Caller.Func.Name() == "main" && e.Site != nil && e.Site.Common().StaticCallee() == nil {
+				if !res.Approximate[e] {
+					t.Errorf("CHAFallback %v: edge %s is not approximate", cha, e)
+				}
+				edges = append(edges, e.Callee.Func.String())
+			}
+			return nil
+		})
+		sort.Strings(edges)
+		var want []string
+		if cha {
+			want = []string{"(*main.A).M", "(*main.B).M", "(main.A).M", "main.f1", "main.f2"}
+		}
+		if !reflect.DeepEqual(edges, want) {
+			t.Errorf("CHAFallback %v: dynamic calls of main to %v, want %v", cha, edges, want)
+		}
+		if cha {
+			// The arguments flow to the params of the callees.
+			if got, want := labels(res, sampleParam(t, pkg, "main.f1", "p")), []string{"main.main:t0"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("the param of f1 points to %v, want %v", got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package pa
+
+import (
+	"go/types"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// A ContextPolicy reports whether fn is analyzed context-sensitively,
+// i.e. cloned per context chosen by the ContextSelector.
+// Other functions are analyzed once, in the empty context.
+type ContextPolicy func(fn *ssa.Function) bool
+
+// selectiveContextPolicy reports whether fn is cloned per context.
+func (a *analysis) selectiveContextPolicy(fn *ssa.Function) bool {
+	return a.opts.ContextPolicy == nil || a.opts.ContextPolicy(fn)
+}
+
+// InPackages clones only the functions of the packages with the given
+// import paths, or beneath them.
+func InPackages(paths ...string) ContextPolicy {
+	return func(fn *ssa.Function) bool {
+		path := funcPkgPath(fn)
+		for _, p := range paths {
+			if path == p || strings.HasPrefix(path, p+"/") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// NotStd clones every function except those of the standard library,
+// the packages whose path has no dot in its first element, like "fmt" or
+// "net/http". The packages sharing the first element of the path of a main
+// package of the program, as those of a module named "myapp", are not std.
+func NotStd() ContextPolicy {
+	var mu sync.Mutex
+	mains := make(map[*ssa.Program]map[string]bool) // the first elements of the paths of the main packages
+	return func(fn *ssa.Function) bool {
+		if fn.Pkg != nil && fn.Pkg.Pkg.Name() == "main" {
+			return true
+		}
+		path := funcPkgPath(fn)
+		if path == "" {
+			return false
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		m, ok := mains[fn.Prog]
+		if !ok {
+			m = make(map[string]bool)
+			for _, pkg := range fn.Prog.AllPackages() {
+				if pkg.Pkg.Name() == "main" {
+					m[firstPathElem(pkg.Pkg.Path())] = true
+				}
+			}
+			mains[fn.Prog] = m
+		}
+		return !isStdPath(path) || m[firstPathElem(path)]
+	}
+}
+
+// HasDynamicParams clones only the functions with an interface-
+// or function-typed parameter (including the receiver),
+// whose callees depend on the caller.
+func HasDynamicParams() ContextPolicy {
+	return func(fn *ssa.Function) bool {
+		for _, T := range summaryParams(fn.Signature) {
+			switch T.Underlying().(type) {
+			case *types.Interface, *types.Signature:
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AllOf clones the functions all of policies clone.
+func AllOf(policies ...ContextPolicy) ContextPolicy {
+	return func(fn *ssa.Function) bool {
+		for _, p := range policies {
+			if !p(fn) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// AnyOf clones the functions any of policies clones.
+func AnyOf(policies ...ContextPolicy) ContextPolicy {
+	return func(fn *ssa.Function) bool {
+		for _, p := range policies {
+			if p(fn) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not clones the functions policy does not clone.
+func Not(policy ContextPolicy) ContextPolicy {
+	return func(fn *ssa.Function) bool {
+		return !policy(fn)
+	}
+}
+
+// funcPkgPath returns the import path of the package of fn,
+// or that of its enclosing function for closures and wrappers;
+// "" if unknown.
+func funcPkgPath(fn *ssa.Function) string {
+	if fn.Pkg != nil {
+		return fn.Pkg.Pkg.Path()
+	}
+	if obj := fn.Object(); obj != nil && obj.Pkg() != nil {
+		return obj.Pkg().Path()
+	}
+	return ""
+}
+
+// isStdPath reports whether path is the import path of a standard
+// library package, i.e. its first element contains no dot.
+func isStdPath(path string) bool {
+	return !strings.Contains(firstPathElem(path), ".")
+}
+
+// firstPathElem returns the first element of the import path path.
+func firstPathElem(path string) string {
+	first, _, _ := strings.Cut(path, "/")
+	return first
+}
@@ -0,0 +1,91 @@
+package pa
+
+import (
+	"go/types"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const policySample = `package main
+
+type F func()
+
+func (f F) Call() { f() }
+
+func dyn(x interface{})
+func cb(f func())
+func static(p *int) *int
+func static2(p *int) *int { return p }
+
+func main() {}
+`
+
+func TestHasDynamicParams(t *testing.T) {
+	pkg := buildSample(t, policySample)
+	policy := HasDynamicParams()
+	for fn, want := range map[string]bool{
+		"main.dyn":      true, // without body, hence without Params
+		"main.cb":       true,
+		"main.static":   false,
+		"main.static2":  false,
+		"(main.F).Call": true,
+	} {
+		if got := policy(sampleFunc(t, pkg, fn)); got != want {
+			t.Errorf("HasDynamicParams(%s) = %v, want %v", fn, got, want)
+		}
+	}
+}
+
+func TestNotStd(t *testing.T) {
+	pkg := buildSample(t, policySample)
+	prog := pkg.Prog
+	prog.CreatePackage(types.NewPackage("myapp/cmd/tool", "main"), nil, nil, true)
+	policy := NotStd()
+	for path, want := range map[string]bool{
+		"fmt":                     false,
+		"net/http":                false,
+		"internal/abi":            false,
+		"vendor/golang.org/x/net": false,
+		"example.com/x":           true,
+		"gopkg.in/yaml.v3":        true,
+		"myapp/internal/x":        true, // the module of the main package myapp/cmd/tool
+	} {
+		fn := &ssa.Function{
+			Prog:      prog,
+			Pkg:       &ssa.Package{Prog: prog, Pkg: types.NewPackage(path, "x")},
+			Signature: types.NewSignatureType(nil, nil, nil, nil, nil, false),
+		}
+		if got := policy(fn); got != want {
+			t.Errorf("NotStd() of a function of %s = %v, want %v", path, got, want)
+		}
+	}
+	if !policy(sampleFunc(t, pkg, "main.static")) {
+		t.Errorf("NotStd() does not clone the functions of the main package")
+	}
+}
+
+func TestContextPolicies(t *testing.T) {
+	pkg := buildSample(t, selectorSample)
+	r1, r2 := sampleParam(t, pkg, "main.use", "r1"), sampleParam(t, pkg, "main.use", "r2")
+	for _, test := range []struct {
+		name   string
+		policy ContextPolicy
+		alias  bool // whether r1 and r2 may alias, i.e. get and Get are not cloned
+	}{
+		{"nil", nil, false},
+		{"InPackages(main)", InPackages("main"), false},
+		{"InPackages(other)", InPackages("other"), true},
+		{"Not(InPackages(main))", Not(InPackages("main")), true},
+		{"AnyOf", AnyOf(InPackages("other"), InPackages("main")), false},
+		{"AllOf", AllOf(InPackages("main"), HasDynamicParams()), true},
+	} {
+		res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{K: 2, ContextPolicy: test.policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.MayAlias(r1, r2); got != test.alias {
+			t.Errorf("%s: r1 and r2 alias: %v, want %v", test.name, got, test.alias)
+		}
+	}
+}
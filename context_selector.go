@@ -0,0 +1,108 @@
+package pa
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// A ContextSelector chooses the context a callee is analyzed under.
+// It is consulted for static calls, interface method invocations (invokeRule)
+// and calls through function values (fpRule).
+type ContextSelector interface {
+	// SelectContext returns the context for the callee of inv.
+	SelectContext(inv *Invocation) Context
+
+	// ReceiverSensitive reports whether the selector uses Invocation.Receiver.
+	// If so, statically dispatched calls of pointer-receiver methods are
+	// resolved per receiver object, as interface invocations are.
+	ReceiverSensitive() bool
+}
+
+// An Invocation describes a call edge for which a context is selected.
+type Invocation struct {
+	Caller  *ssa.Function
+	Context Context             // context of the caller
+	Site    ssa.CallInstruction // the call instruction, nil for calls made by intrinsics
+	Callee  *ssa.Function
+
+	// Receiver is the abstract receiver object of a method call,
+	// or nil for function calls and receivers that are not objects.
+	Receiver *Label
+
+	a *analysis
+}
+
+// Push returns ctx extended with elem,
+// keeping at most the k most recent elements.
+func (inv *Invocation) Push(ctx Context, elem interface{}, k int) Context {
+	return inv.a.pushContext(ctx, elem, k)
+}
+
+// CallSiteSensitive is k-call-site sensitivity (k-CFA):
+// contexts are strings of the k most recent call sites.
+// Calls made by intrinsics, e.g. reflect.Value.Call, keep the context of the
+// intrinsic, which tells its call site.
+type CallSiteSensitive struct {
+	K int
+}
+
+func (s CallSiteSensitive) SelectContext(inv *Invocation) Context {
+	if inv.Site == nil {
+		return inv.Context
+	}
+	return inv.Push(inv.Context, inv.Site, s.K)
+}
+
+func (s CallSiteSensitive) ReceiverSensitive() bool { return false }
+
+// ObjectSensitive is k-object sensitivity: a method is analyzed per
+// receiver object, with contexts made of the heap context of the
+// receiver followed by its allocation site.
+// Calls without a receiver object keep the context of the caller.
+type ObjectSensitive struct {
+	K int
+}
+
+func (s ObjectSensitive) SelectContext(inv *Invocation) Context {
+	if inv.Receiver == nil {
+		return inv.Context
+	}
+	return inv.Push(inv.Receiver.HeapContext(), inv.Receiver.Value(), s.K)
+}
+
+func (s ObjectSensitive) ReceiverSensitive() bool { return true }
+
+// TypeSensitive is k-type sensitivity: as ObjectSensitive, but the
+// allocation site of the receiver is abstracted by the type containing it,
+// i.e. the receiver type of the allocating method.
+// Objects allocated by plain functions are abstracted by the function,
+// global objects by their own type.
+type TypeSensitive struct {
+	K int
+}
+
+func (s TypeSensitive) SelectContext(inv *Invocation) Context {
+	if inv.Receiver == nil {
+		return inv.Context
+	}
+	return inv.Push(inv.Receiver.HeapContext(), containingType(inv.Receiver), s.K)
+}
+
+func (s TypeSensitive) ReceiverSensitive() bool { return true }
+
+// containingType returns the type sensitivity element of the object l.
+func containingType(l *Label) interface{} {
+	fn := l.Func()
+	if fn == nil {
+		return l.Type()
+	}
+	if recv := fn.Signature.Recv(); recv != nil {
+		t := recv.Type()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		return t
+	}
+	return fn
+}
@@ -0,0 +1,58 @@
+package pa
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const selectorSample = `package main
+
+type A struct{ p *int }
+
+func (a *A) Get() *int { return a.p }
+
+type F1 struct{}
+type F2 struct{}
+
+func (F1) mk() *A { return &A{p: new(int)} }
+func (F2) mk() *A { return &A{p: new(int)} }
+
+func (a *A) get() *int { return a.Get() }
+
+func use(r1, r2, r3, r4 *int) {}
+
+func main() {
+	r1 := (&A{p: new(int)}).get()
+	r2 := (&A{p: new(int)}).get()
+	r3 := F1{}.mk().get()
+	r4 := F2{}.mk().get()
+	use(r1, r2, r3, r4)
+}
+`
+
+func TestContextSelectors(t *testing.T) {
+	pkg := buildSample(t, selectorSample)
+	r := func(name string) ssa.Value { return sampleParam(t, pkg, "main.use", name) }
+	for _, test := range []struct {
+		name     string
+		selector ContextSelector
+		r12, r34 bool // whether r1 and r2, r3 and r4 may alias
+	}{
+		{"1-cfa", CallSiteSensitive{K: 1}, true, true},
+		{"2-cfa", CallSiteSensitive{K: 2}, false, false},
+		{"1-obj", ObjectSensitive{K: 1}, false, false},
+		{"1-type", TypeSensitive{K: 1}, true, false}, // the objects of r1 and r2 are both allocated by main
+	} {
+		res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{ContextSelector: test.selector})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.MayAlias(r("r1"), r("r2")); got != test.r12 {
+			t.Errorf("%s: r1 and r2 alias: %v, want %v", test.name, got, test.r12)
+		}
+		if got := res.MayAlias(r("r3"), r("r4")); got != test.r34 {
+			t.Errorf("%s: r3 and r4 alias: %v, want %v", test.name, got, test.r34)
+		}
+	}
+}
@@ -1,45 +1,159 @@
 package pa
 
 import (
+	"fmt"
+	"strings"
+
 	"golang.org/x/tools/go/ssa"
 )
 
-// k-CFA context sensitivity.
-// this const value define how deep a context could take.
-// at least 1
-const level int = 1
+// A Context distinguishes the clones of a function (and of the objects it
+// allocates): a string of at most k context elements, chosen by a ContextSelector.
+// Elements are call sites (ssa.CallInstruction) for call-site sensitivity,
+// allocation sites (ssa.Value) for object sensitivity, or types (types.Type)
+// and functions (*ssa.Function) for type sensitivity.
+//
+// Contexts are interned by the analysis, so equal element strings
+// yield equal Contexts and may be used as map keys.
+type Context struct {
+	cs *ctxstring // nil for the empty context
+}
 
-// selective context-sensitivity policy.
-func selectiveContextPolicy(fn *ssa.Function) bool {
-	return true
+// ctxstring is a node in the trie of interned context strings.
+type ctxstring struct {
+	parent *ctxstring  // context without the most recent element
+	elem   interface{} // the most recent element
 }
 
-type context struct {
-	callstring [level]ssa.CallInstruction
+type ctxstringKey struct {
+	parent *ctxstring
+	elem   interface{}
 }
 
-func NewContext() context {
-	return context{[level]ssa.CallInstruction{}}
+// NewContext returns the empty context,
+// used for entry points and context-insensitive functions.
+func NewContext() Context {
+	return Context{}
 }
 
-func (caller_context context) GenContext(l ssa.CallInstruction) context {
-	var new_context [level]ssa.CallInstruction
-	for i := 1; i < level; i++ {
-		new_context[i-1] = caller_context.callstring[i]
+// pushContext returns ctx extended with elem,
+// keeping at most the k most recent elements.
+func (a *analysis) pushContext(ctx Context, elem interface{}, k int) Context {
+	if k <= 0 {
+		return NewContext()
+	}
+	elems := append(ctx.Elements(), elem)
+	if len(elems) > k {
+		elems = elems[len(elems)-k:]
+	}
+	var cs *ctxstring
+	for _, e := range elems {
+		key := ctxstringKey{cs, e}
+		next, ok := a.contexts[key]
+		if !ok {
+			next = &ctxstring{cs, e}
+			a.contexts[key] = next
+		}
+		cs = next
+	}
+	return Context{cs}
+}
+
+// Len returns the number of elements of c.
+func (c Context) Len() int {
+	n := 0
+	for cs := c.cs; cs != nil; cs = cs.parent {
+		n++
+	}
+	return n
+}
+
+// Elements returns the elements of c, outermost first.
+func (c Context) Elements() []interface{} {
+	var elems []interface{}
+	for cs := c.cs; cs != nil; cs = cs.parent {
+		elems = append(elems, cs.elem)
+	}
+	for i, j := 0, len(elems)-1; i < j; i, j = i+1, j-1 {
+		elems[i], elems[j] = elems[j], elems[i]
 	}
-	new_context[level-1] = l
-	return context{new_context}
+	return elems
+}
+
+func (c Context) String() string {
+	var buf strings.Builder
+	buf.WriteByte('[')
+	for i, elem := range c.Elements() {
+		if i > 0 {
+			buf.WriteString("; ")
+		}
+		switch e := elem.(type) {
+		case ssa.CallInstruction:
+			buf.WriteString(e.String())
+		case *ssa.Function:
+			buf.WriteString(e.String())
+		case ssa.Value:
+			if fn := e.Parent(); fn != nil {
+				buf.WriteString(fn.String() + ":")
+			}
+			buf.WriteString(e.Name())
+		default:
+			fmt.Fprint(&buf, e)
+		}
+	}
+	buf.WriteByte(']')
+	return buf.String()
 }
 
 // denotes a reachable func with context
 type funcnode struct {
 	fn           *ssa.Function // func ir info
 	obj          nodeid        // start of this function object block
-	func_context context
+	func_context Context
+
+	// node for each local ssa.Value, kept after constraint generation for queries
+	localval map[ssa.Value]nodeid
+}
+
+// funcObject returns the function object of inv.Callee in the context selected for inv.
+// On first use of that context, the object is created and the clone made reachable.
+func (a *analysis) funcObject(inv *Invocation) nodeid {
+	fn := inv.Callee
+	inv.a = a
+
+	new_context := NewContext()
+	if a.selectiveContextPolicy(fn) {
+		new_context = a.selector.SelectContext(inv)
+	}
+	if _, ok := a.csfuncobj[fn]; !ok {
+		a.csfuncobj[fn] = make(map[Context]nodeid, 0)
+	}
+	if obj, ok := a.csfuncobj[fn][new_context]; ok {
+		return obj
+	}
+	if new_context = a.limitContext(fn, new_context); new_context.Len() == 0 {
+		if obj, ok := a.csfuncobj[fn][new_context]; ok {
+			return obj
+		}
+	}
+
+	obj := a.makeFunctionObject(fn)
+	a.csfuncobj[fn][new_context] = obj
+	new_funcnode := &funcnode{fn: fn, obj: obj, func_context: new_context}
+
+	// Set called function obj's obj field.
+	a.nodes[obj].obj.funcn = new_funcnode
+
+	// Add newly added funcnode into reachable queue
+	a.addReachable(new_funcnode)
+	return obj
 }
 
 // wrapper. duplicate edges due to the elimination of context
-func (a *analysis) addCallGraphEdge(caller *ssa.Function, callsite ssa.CallInstruction, callee *ssa.Function) {
+// are merged in the context-insensitive call graph;
+// the context-sensitive one keeps an edge per pair of funcnodes.
+func (a *analysis) addCallGraphEdge(caller_node *funcnode, callsite ssa.CallInstruction, callee_node *funcnode) {
+	caller, callee := caller_node.fn, callee_node.fn
 	if _, ok := a.callgraph[caller]; !ok {
 		a.callgraph[caller] = make(map[ssa.CallInstruction]map[*ssa.Function]bool)
 	}
@@ -47,4 +161,44 @@ func (a *analysis) addCallGraphEdge(caller *ssa.Function, callsite ssa.CallInstr
 		a.callgraph[caller][callsite] = make(map[*ssa.Function]bool)
 	}
 	a.callgraph[caller][callsite][callee] = true
+
+	key := csEdgeKey{caller_node, callsite, callee_node}
+	if !a.csedgeSeen[key] || a.csapprox[key] {
+		a.traceCallEdge(key, false)
+	}
+	delete(a.csapprox, key)
+	if !a.csedgeSeen[key] {
+		a.csedgeSeen[key] = true
+		a.csedges = append(a.csedges, key)
+	}
+}
+
+// addApproxCallGraphEdge adds an edge resolved by CHA,
+// approximate unless the solver also resolves it.
+func (a *analysis) addApproxCallGraphEdge(caller_node *funcnode, callsite ssa.CallInstruction, callee_node *funcnode) {
+	caller, callee := caller_node.fn, callee_node.fn
+	if _, ok := a.callgraph[caller]; !ok {
+		a.callgraph[caller] = make(map[ssa.CallInstruction]map[*ssa.Function]bool)
+	}
+	if _, ok := a.callgraph[caller][callsite]; !ok {
+		a.callgraph[caller][callsite] = make(map[*ssa.Function]bool)
+	}
+	if _, ok := a.callgraph[caller][callsite][callee]; !ok {
+		a.callgraph[caller][callsite][callee] = false
+	}
+
+	key := csEdgeKey{caller_node, callsite, callee_node}
+	if !a.csedgeSeen[key] {
+		a.csedgeSeen[key] = true
+		a.csedges = append(a.csedges, key)
+		a.csapprox[key] = true
+		a.traceCallEdge(key, true)
+	}
+}
+
+func (a *analysis) traceCallEdge(key csEdgeKey, approximate bool) {
+	if a.tracer != nil {
+		a.tracer.CallEdgeAdded(key.caller.fn, key.caller.func_context, key.site,
+			key.callee.fn, key.callee.func_context, approximate)
+	}
 }
@@ -0,0 +1,82 @@
+package pa
+
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// A CSCallGraph is the context-sensitive call graph: its nodes are the
+// funcnodes, i.e. the clones of the reachable functions, one per context.
+// Unlike Result.CallGraph, it tells which clone calls which.
+type CSCallGraph struct {
+	Root  *CSNode   // the synthetic root, calling the entry points
+	Nodes []*CSNode // all nodes, in discovery order
+}
+
+// A CSNode is a function analyzed in one context.
+type CSNode struct {
+	ID      int // index in CSCallGraph.Nodes
+	Func    *ssa.Function
+	Context Context
+	In      []*CSEdge // unordered set of incoming call edges
+	Out     []*CSEdge // unordered set of outgoing call edges
+}
+
+// A CSEdge is a call from a clone of the caller to a clone of the callee.
+type CSEdge struct {
+	Caller *CSNode
+	Site   ssa.CallInstruction // nil for calls from the root and from intrinsics
+	Callee *CSNode
+
+	// Approximate reports an edge resolved by CHA only, see Options.CHAFallback.
+	Approximate bool
+}
+
+func (n *CSNode) String() string {
+	return fmt.Sprintf("n%d:%s%s", n.ID, n.Func, n.Context)
+}
+
+func (e *CSEdge) String() string {
+	return fmt.Sprintf("%s --> %s", e.Caller, e.Callee)
+}
+
+// CallString returns the call string leading to the callee along the edge:
+// the context elements of the caller followed by the call site.
+// For call-site sensitivity, its suffix is the context of the callee.
+func (e *CSEdge) CallString() []interface{} {
+	elems := e.Caller.Context.Elements()
+	if e.Site != nil {
+		elems = append(elems, e.Site)
+	}
+	return elems
+}
+
+type csEdgeKey struct {
+	caller *funcnode
+	site   ssa.CallInstruction
+	callee *funcnode
+}
+
+// csCallGraph builds the context-sensitive call graph from the recorded edges.
+func (a *analysis) csCallGraph() *CSCallGraph {
+	g := new(CSCallGraph)
+	nodes := make(map[*funcnode]*CSNode)
+	node := func(fc *funcnode) *CSNode {
+		n, ok := nodes[fc]
+		if !ok {
+			n = &CSNode{ID: len(g.Nodes), Func: fc.fn, Context: fc.func_context}
+			nodes[fc] = n
+			g.Nodes = append(g.Nodes, n)
+		}
+		return n
+	}
+
+	g.Root = node(a.root)
+	for _, key := range a.csedges {
+		e := &CSEdge{Caller: node(key.caller), Site: key.site, Callee: node(key.callee), Approximate: a.csapprox[key]}
+		e.Caller.Out = append(e.Caller.Out, e)
+		e.Callee.In = append(e.Callee.In, e)
+	}
+	return g
+}
@@ -0,0 +1,259 @@
+package pa
+
+// Online cycle elimination, by lazy cycle detection (Hardekopf & Lin, 2007).
+//
+// The nodes of a cycle of flow edges have the same points-to set at the
+// fixpoint, so they are collapsed into one: they share a single nodeState,
+// that of the representative, and a.nodes[id] keeps working for all of them.
+// When the solver propagates along an edge n -> m to no effect, and finds
+// pts(m) = pts(n), the facts are likely going around a cycle: m is queued,
+// and the strongly connected components reachable from the queued nodes are
+// searched for and collapsed, all at once. Each edge queues its target once,
+// and the searches are spaced out by the propagations they may save.
+// A collapsed cycle propagates the facts coming later once, instead of
+// once per edge.
+
+// A nodeState is the solver state of a node, shared by the nodes of a collapsed cycle.
+type nodeState struct {
+	rep     nodeid   // the representative of the nodes sharing the state
+	members []nodeid // the nodes sharing the state, nil if only rep
+
+	fly_solve []rule  // on-the-fly-solved rule attached to this node
+	flow_to   nodeset // all pointer-like valuenode it may flow to
+	pts       ptset   // pt(n)
+	prev_pts  ptset   // pt(n) in previous iteration, for difference propagation
+}
+
+type lcdEdge struct {
+	src, dst nodeid // representatives
+}
+
+// cycleElimination reports whether copy cycles are collapsed. Provenance
+// turns it off: a witness may pass through any node of a cycle, and merging
+// them would lose the copies it is made of.
+func (a *analysis) cycleElimination() bool {
+	return a.opts.CycleElimination && !a.opts.Provenance
+}
+
+// checkCycle notes that the propagation from n to mid made their points-to
+// sets equal, if so, and queues mid for cycle detection once per edge.
+func (a *analysis) checkCycle(n *node, mid nodeid) {
+	m := a.nodes[mid]
+	if m.nodeState == n.nodeState {
+		return
+	}
+	key := lcdEdge{n.rep, m.rep}
+	if a.lcdSeen[key] || !m.pts.set().Equals(&n.pts.set().Sparse) {
+		return
+	}
+	a.lcdSeen[key] = true
+	a.lcdQueue = append(a.lcdQueue, mid)
+}
+
+// collapseCycles collapses the cycles reachable from the queued nodes,
+// searched at once. The searches are spaced out so that they visit no more
+// nodes than the propagations done since the last one.
+func (a *analysis) collapseCycles() {
+	if len(a.lcdQueue) == 0 || a.stats.Propagations < a.lcdNext {
+		return
+	}
+	roots := a.lcdQueue
+	a.lcdQueue = a.lcdQueue[:0]
+	sccs, visited := a.findSCCs(roots)
+	a.lcdNext = a.stats.Propagations + visited
+	a.stats.CycleSearches++
+	for _, scc := range sccs {
+		a.collapse(scc)
+	}
+}
+
+// findSCCs returns the non-trivial strongly connected components of the
+// flow graph of representatives reachable from roots, by Tarjan's algorithm,
+// and the number of nodes visited.
+func (a *analysis) findSCCs(roots []nodeid) ([][]nodeid, int) {
+	sc := &a.scc
+	for len(sc.index) < len(a.nodes) {
+		sc.index = append(sc.index, 0)
+		sc.low = append(sc.low, 0)
+		sc.onStack = append(sc.onStack, false)
+	}
+
+	var sccs [][]nodeid
+	var visit func(v nodeid)
+	visit = func(v nodeid) {
+		sc.visited = append(sc.visited, v)
+		sc.index[v] = len(sc.visited)
+		sc.low[v] = sc.index[v]
+		sc.stack = append(sc.stack, v)
+		sc.onStack[v] = true
+
+		// The successors of v go on top of sc.succs, and stay valid
+		// even if it grows: the deeper visits do not write below.
+		i := len(sc.succs)
+		sc.succs = a.nodes[v].flow_to.AppendTo(sc.succs)
+		for _, y := range sc.succs[i:len(sc.succs):len(sc.succs)] {
+			w := a.nodes[y].rep
+			if sc.index[w] == 0 {
+				visit(w)
+				if sc.low[w] < sc.low[v] {
+					sc.low[v] = sc.low[w]
+				}
+			} else if sc.onStack[w] && sc.index[w] < sc.low[v] {
+				sc.low[v] = sc.index[w]
+			}
+		}
+		sc.succs = sc.succs[:i]
+
+		if sc.low[v] == sc.index[v] {
+			i := len(sc.stack) - 1
+			for sc.stack[i] != v {
+				i--
+			}
+			scc := sc.stack[i:]
+			for _, w := range scc {
+				sc.onStack[w] = false
+			}
+			if len(scc) > 1 {
+				sccs = append(sccs, append([]nodeid(nil), scc...))
+			}
+			sc.stack = sc.stack[:i]
+		}
+	}
+	for _, root := range roots {
+		if root := a.nodes[root].rep; sc.index[root] == 0 {
+			visit(root)
+		}
+	}
+
+	visited := len(sc.visited)
+	for _, v := range sc.visited {
+		sc.index[v] = 0
+	}
+	sc.visited = sc.visited[:0]
+	return sccs, visited
+}
+
+// sccSearch is the state of findSCCs, indexed by nodeid, reused across searches.
+type sccSearch struct {
+	index, low []int // 1 + the visit order of the visited nodes, else 0
+	onStack    []bool
+	visited    []nodeid
+	stack      []nodeid
+	succs      []int
+}
+
+// collapse merges the states of the representatives in scc into that of the least one.
+func (a *analysis) collapse(scc []nodeid) {
+	r := scc[0]
+	for _, v := range scc {
+		if v < r {
+			r = v
+		}
+	}
+	a.stats.CollapsedNodes += a.merge(r, scc)
+}
+
+// merge merges the states of the nodes vs into that of the representative r,
+// and returns the number of nodes merged.
+func (a *analysis) merge(r nodeid, vs []nodeid) int {
+	rs := a.nodes[r].nodeState
+	group := map[*nodeState]bool{rs: true}
+	states := []*nodeState{rs}
+	for _, v := range vs {
+		if s := a.nodes[v].nodeState; !group[s] {
+			group[s] = true
+			states = append(states, s)
+		}
+	}
+	if len(states) == 1 {
+		return 0
+	}
+
+	// Each state has yet to see the facts of the others, and its own not
+	// propagated yet: its flow edges out of the group and its rules fire on
+	// those only. The merged state sees them all.
+	var union nodeset
+	for _, s := range states {
+		union.addAll(s.pts.set())
+	}
+	for _, s := range states {
+		a.fireUnseen(s, &union, group)
+	}
+
+	merged := 0
+	if rs.members == nil {
+		rs.members = []nodeid{r}
+	}
+	for _, s := range states[1:] {
+		rs.pts.addAll(s.pts.set())
+		rs.fly_solve = append(rs.fly_solve, s.fly_solve...)
+		rs.flow_to.addAll(&s.flow_to)
+		s.pts.clear()
+		s.prev_pts.clear()
+
+		members := s.members
+		if members == nil {
+			members = []nodeid{s.rep}
+		}
+		for _, id := range members {
+			a.nodes[id].nodeState = rs
+		}
+		rs.members = append(rs.members, members...)
+		merged += len(members)
+	}
+	rs.prev_pts.assign(rs.pts)
+
+	// Redirect the flow edges to representatives, dropping those to r.
+	var flow_to nodeset
+	for _, y := range rs.flow_to.AppendTo(nil) {
+		if w := a.nodes[y].rep; w != r {
+			flow_to.add(w)
+		}
+	}
+	rs.flow_to.Copy(&flow_to.Sparse)
+	a.flowVersion++
+
+	return merged
+}
+
+// A ruleFire is the firing of rules on facts they have not seen, queued
+// by merge and addRule: they happen amid the generation of functions,
+// which rules may trigger.
+type ruleFire struct {
+	rules []rule
+	delta nodeset
+}
+
+// fireUnseen propagates the facts of pts that s has not seen along its flow
+// edges to the nodes out of group, and queues the firing of its rules on them.
+func (a *analysis) fireUnseen(s *nodeState, pts *nodeset, group map[*nodeState]bool) {
+	f := new(ruleFire)
+	f.delta.Difference(&pts.Sparse, &s.prev_pts.set().Sparse)
+	if f.delta.IsEmpty() {
+		return
+	}
+	f.rules = append(f.rules, s.fly_solve...)
+	for _, y := range s.flow_to.AppendTo(nil) {
+		if group[a.nodes[y].nodeState] {
+			continue
+		}
+		a.stats.Propagations++
+		if a.nodes[y].pts.addAll(&f.delta) {
+			a.addWork(nodeid(y))
+		}
+	}
+	if len(f.rules) > 0 {
+		a.ruleFires = append(a.ruleFires, f)
+	}
+}
+
+// fireQueued fires the queued rules.
+func (a *analysis) fireQueued() {
+	for len(a.ruleFires) > 0 {
+		f := a.ruleFires[0]
+		a.ruleFires = a.ruleFires[1:]
+		for _, rule := range f.rules {
+			rule.addflow(a, &f.delta)
+		}
+	}
+}
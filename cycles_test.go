@@ -0,0 +1,88 @@
+package pa
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// cycleProgram returns a program whose objects go around a ring of n
+// globals one after the other: each object, once called, allocates the next.
+func cycleProgram(n int) string {
+	var b strings.Builder
+	b.WriteString("package main\n\ntype T struct{ f func() }\n\nvar cond bool\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "var g%d *T\n", i)
+	}
+	for i := 0; i < n; i++ {
+		if i == n-1 {
+			fmt.Fprintf(&b, "func step%d() { g0 = g%d; g%d.f() }\n", i, i, i)
+		} else {
+			fmt.Fprintf(&b, "func step%d() { g%d = g%d }\n", i, i+1, i)
+		}
+		fmt.Fprintf(&b, "func alloc%d() { g0 = &T{f: alloc%d} }\n", i, (i+1)%n)
+	}
+	b.WriteString("func main() {\n\talloc0()\n\tfor cond {\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "\t\tstep%d()\n", i)
+	}
+	b.WriteString("\t}\n}\n")
+	return b.String()
+}
+
+func TestCycleElimination(t *testing.T) {
+	samples := map[string]string{
+		"ring":      cycleProgram(50),
+		"synthetic": syntheticProgram(12),
+	}
+	for name, src := range samples {
+		pkg := buildSample(t, src)
+		for _, k := range []int{1, 2} {
+			want := solution(t, pkg, &Options{K: k})
+			if got := solution(t, pkg, &Options{K: k, CycleElimination: true}); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s, K %d: got\n%s\nwant\n%s", name, k, strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		}
+	}
+
+	pkg := buildSample(t, cycleProgram(50))
+	stats := func(opts *Options) *Stats {
+		res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Stats
+	}
+	without, with := stats(&Options{K: 1}), stats(&Options{K: 1, CycleElimination: true})
+	if with.CollapsedNodes == 0 || with.Propagations*2 > without.Propagations {
+		t.Errorf("ring: %d propagations, %d nodes collapsed; %d propagations without cycle elimination",
+			with.Propagations, with.CollapsedNodes, without.Propagations)
+	}
+}
+
+func BenchmarkCycles(b *testing.B) {
+	samples := []struct{ name, src string }{
+		{"ring", cycleProgram(200)},
+		{"synthetic", syntheticProgram(60)},
+	}
+	for _, sample := range samples {
+		pkg := buildSample(b, sample.src)
+		for _, cyc := range []bool{false, true} {
+			b.Run(fmt.Sprintf("%s/CycleElimination=%v", sample.name, cyc), func(b *testing.B) {
+				var stats *Stats
+				for i := 0; i < b.N; i++ {
+					res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{K: 2, CycleElimination: cyc})
+					if err != nil {
+						b.Fatal(err)
+					}
+					stats = res.Stats
+				}
+				b.ReportMetric(float64(stats.Propagations), "props")
+				b.ReportMetric(float64(stats.CollapsedNodes), "collapsed")
+			})
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package pa
+
+import (
+	"fmt"
+	"go/token"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// A DiagnosticKind classifies the places where the analysis gave up on soundness.
+type DiagnosticKind int
+
+const (
+	// UnsafePointerConversion is a *T -> unsafe.Pointer conversion:
+	// the flow of the pointer is lost.
+	UnsafePointerConversion DiagnosticKind = iota
+
+	// UintptrConversion is an unsafe.Pointer <-> uintptr conversion,
+	// treated as a no-op.
+	UintptrConversion
+
+	// UnmodeledFunction is a function of package reflect or runtime
+	// that has no model, treated as having no effect.
+	UnmodeledFunction
+
+	// GenericBody is a generic function body, or an instantiation wrapper,
+	// that is not analyzed.
+	GenericBody
+
+	// BodylessFunction is a function without body and without summary,
+	// treated as having no effect unless Options.ConservativeBodyless is set.
+	BodylessFunction
+
+	// UnsupportedConstruct is a construct the analysis does not support,
+	// skipped as requested by Options.SkipUnsupported.
+	UnsupportedConstruct
+
+	// UnmatchedModel is an entry of Options.ModelFiles in no package of the
+	// program, ignored. Its Message names the function.
+	UnmatchedModel
+)
+
+var diagnosticKindNames = [...]string{
+	UnsafePointerConversion: "unsafe.Pointer conversion",
+	UintptrConversion:       "uintptr conversion",
+	UnmodeledFunction:       "unmodeled function",
+	GenericBody:             "generic body",
+	BodylessFunction:        "body-less function",
+	UnsupportedConstruct:    "unsupported construct",
+	UnmatchedModel:          "unmatched model",
+}
+
+func (k DiagnosticKind) String() string {
+	if int(k) < len(diagnosticKindNames) {
+		return diagnosticKindNames[k]
+	}
+	return fmt.Sprintf("DiagnosticKind(%d)", int(k))
+}
+
+// A Diagnostic reports a place where the analysis gave up on soundness,
+// so that the points-to sets and the call graph may be incomplete there.
+type Diagnostic struct {
+	Kind    DiagnosticKind
+	Pos     token.Pos     // position of the instruction or the function, if known
+	Func    *ssa.Function // the function containing the instruction, or the function given up on
+	Message string
+}
+
+type diagnosticKey struct {
+	kind DiagnosticKind
+	pos  token.Pos
+	fn   *ssa.Function
+	msg  string
+}
+
+// diagnose records a diagnostic, once for all the clones of fn.
+func (a *analysis) diagnose(kind DiagnosticKind, pos token.Pos, fn *ssa.Function, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	key := diagnosticKey{kind, pos, fn, msg}
+	if a.diagnosticSeen[key] {
+		return
+	}
+	a.diagnosticSeen[key] = true
+	a.diagnostics = append(a.diagnostics, Diagnostic{
+		Kind:    kind,
+		Pos:     pos,
+		Func:    fn,
+		Message: msg,
+	})
+}
@@ -0,0 +1,77 @@
+package pa
+
+import (
+	"fmt"
+	"go/token"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// An UnsupportedError is returned by Analyze for a construct the analysis
+// does not support, such as an unknown SSA instruction or an ill-formed summary,
+// or for a violated invariant of the analysis, with the function and instruction
+// being analyzed. See Options.SkipUnsupported.
+type UnsupportedError struct {
+	Func  *ssa.Function   // the function containing the construct, if known
+	Instr ssa.Instruction // the offending instruction, if any
+	Pos   token.Pos       // position of the construct, if known
+	Msg   string
+}
+
+func (e *UnsupportedError) Error() string {
+	var s string
+	if e.Func != nil {
+		s = e.Func.String() + ": "
+	}
+	s += e.Msg
+	if e.Instr != nil {
+		s += fmt.Sprintf(" (%s)", e.Instr)
+	}
+	return s
+}
+
+// unsupported reports an unsupported construct in fn, with instr if any.
+// With Options.SkipUnsupported it is recorded as a diagnostic and the
+// caller goes on, skipping it; otherwise the analysis is aborted with an
+// UnsupportedError, recovered by Analyze.
+func (a *analysis) unsupported(fn *ssa.Function, instr ssa.Instruction, format string, args ...interface{}) {
+	err := newUnsupportedError(fn, instr, format, args...)
+	if !a.opts.SkipUnsupported {
+		panic(err)
+	}
+	a.diagnose(UnsupportedConstruct, err.Pos, fn, "%s", err.Msg)
+}
+
+// invalid reports input that the constraint generation cannot make sense of,
+// e.g. an ill-typed copy, as an unsupported construct of the function and
+// instruction being generated.
+func (a *analysis) invalid(format string, args ...interface{}) {
+	a.unsupported(a.curfn, a.curinstr, format, args...)
+}
+
+// An invariantError is the panic of a violated invariant of the analysis.
+type invariantError string
+
+// internalError returns the error reporting the panic r, an UnsupportedError
+// or a violated invariant. Other panics, of bugs, are not recovered:
+// internalError panics again with r.
+func (a *analysis) internalError(r interface{}) *UnsupportedError {
+	switch r := r.(type) {
+	case *UnsupportedError:
+		return r
+	case invariantError:
+		return newUnsupportedError(a.curfn, a.curinstr, "internal error: %s", string(r))
+	}
+	panic(r)
+}
+
+func newUnsupportedError(fn *ssa.Function, instr ssa.Instruction, format string, args ...interface{}) *UnsupportedError {
+	err := &UnsupportedError{Func: fn, Instr: instr, Msg: fmt.Sprintf(format, args...)}
+	if instr != nil {
+		err.Pos = instr.Pos()
+	}
+	if err.Pos == token.NoPos && fn != nil {
+		err.Pos = fn.Pos()
+	}
+	return err
+}
@@ -0,0 +1,27 @@
+package pa
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInternalError(t *testing.T) {
+	a := &analysis{}
+	unsupported := &UnsupportedError{Msg: "unsupported"}
+	if got := a.internalError(unsupported); got != unsupported {
+		t.Errorf("internalError(%v) = %v", unsupported, got)
+	}
+	if got := a.internalError(invariantError("not a tagged object: n1")); got.Msg != "internal error: not a tagged object: n1" {
+		t.Errorf("internalError of an invariant = %q", got.Msg)
+	}
+
+	// A bug is not recovered.
+	bug := errors.New("index out of range")
+	defer func() {
+		if r := recover(); r != bug {
+			t.Errorf("internalError(bug) panicked with %v, want %v", r, bug)
+		}
+	}()
+	a.internalError(bug)
+	t.Errorf("internalError(bug) returned")
+}
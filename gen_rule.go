@@ -1,7 +1,6 @@
 package pa
 
 import (
-	"fmt"
 	"go/token"
 	"go/types"
 	"strings"
@@ -19,7 +18,8 @@ func (a *analysis) addflow(dst, src nodeid, sizeof uint32, instr ssa.Value) {
 		return
 	}
 	if src == 0 || dst == 0 {
-		panic(fmt.Sprintf("ill-typed copy dst=n%d src=n%d", dst, src))
+		a.invalid("ill-typed copy dst=n%d src=n%d", dst, src)
+		return
 	}
 	a.auxaddflowN(dst, src, sizeof)
 }
@@ -28,9 +28,9 @@ func (a *analysis) addflow(dst, src nodeid, sizeof uint32, instr ssa.Value) {
 // typeFilter for an interface, untag for a concrete type.
 func (a *analysis) typeAssert(T types.Type, dst, src nodeid, exact bool) {
 	if isInterface(T) {
-		a.nodes[src].fly_solve = append(a.nodes[src].fly_solve, &typeFilterRule{T, dst})
+		a.attachRule(src, &typeFilterRule{T, dst, src})
 	} else {
-		a.nodes[src].fly_solve = append(a.nodes[src].fly_solve, &untagRule{T, dst, exact})
+		a.attachRule(src, &untagRule{T, dst, exact})
 	}
 }
 
@@ -59,6 +59,7 @@ func (a *analysis) genConv(conv *ssa.Convert, cfc *funcnode) {
 	case *types.Pointer:
 		// *T -> unsafe.Pointer?
 		if tDst.Underlying() == tUnsafePtr {
+			a.diagnose(UnsafePointerConversion, conv.Pos(), cfc.fn, "conversion %s -> %s", tSrc, tDst)
 			return // (unsound abandon)
 		}
 
@@ -68,8 +69,11 @@ func (a *analysis) genConv(conv *ssa.Convert, cfc *funcnode) {
 			// Treat unsafe.Pointer->*T conversions like
 			// new(T) and create an unaliased object.
 			if utSrc == tUnsafePtr {
-				obj := a.addNodes(mustDeref(tDst), "unsafe.Pointer conversion")
-				a.endObject(obj, cfc, conv)
+				obj := a.heapObject(cfc, conv)
+				if obj == 0 {
+					obj = a.addNodes(mustDeref(tDst), "unsafe.Pointer conversion")
+					a.endObject(obj, cfc, conv)
+				}
 				a.nodes[res].pts.add(obj)
 				a.worklist.add(res)
 				return
@@ -78,8 +82,11 @@ func (a *analysis) genConv(conv *ssa.Convert, cfc *funcnode) {
 		case *types.Slice:
 			// string -> []byte/[]rune (or named aliases)?
 			if utSrc.Info()&types.IsString != 0 {
-				obj := a.addNodes(sliceToArray(tDst), "convert")
-				a.endObject(obj, cfc, conv)
+				obj := a.heapObject(cfc, conv)
+				if obj == 0 {
+					obj = a.addNodes(sliceToArray(tDst), "convert")
+					a.endObject(obj, cfc, conv)
+				}
 				a.nodes[res].pts.add(obj)
 				a.worklist.add(res)
 				return
@@ -89,11 +96,14 @@ func (a *analysis) genConv(conv *ssa.Convert, cfc *funcnode) {
 			// All basic-to-basic type conversions are no-ops.
 			// This includes uintptr<->unsafe.Pointer conversions,
 			// which we (unsoundly) ignore.
+			if utSrc == tUnsafePtr || tDst.Underlying() == tUnsafePtr {
+				a.diagnose(UintptrConversion, conv.Pos(), cfc.fn, "conversion %s -> %s", tSrc, tDst)
+			}
 			return
 		}
 	}
 
-	panic(fmt.Sprintf("illegal *ssa.Convert %s -> %s: %s", tSrc, tDst, conv.Parent()))
+	a.unsupported(cfc.fn, conv, "illegal *ssa.Convert %s -> %s", tSrc, tDst)
 }
 
 // genAppend generates constraints for a call to append.
@@ -117,9 +127,12 @@ func (a *analysis) genAppend(instr *ssa.Call, cgn *funcnode) {
 	y := instr.Call.Args[1]
 	tArray := sliceToArray(instr.Call.Args[0].Type())
 
-	w := a.nextNode()
-	a.addNodes(tArray, "append")
-	a.endObject(w, cgn, instr)
+	w := a.heapObject(cgn, instr)
+	if w == 0 {
+		w = a.nextNode()
+		a.addNodes(tArray, "append")
+		a.endObject(w, cgn, instr)
+	}
 
 	a.copyElems(cgn, tArray.Elem(), z, y) // *z = *y
 	a.nodes[a.valueNode(z)].pts.add(w)
@@ -161,56 +174,20 @@ func (a *analysis) genBuiltinCall(instr ssa.CallInstruction, cgn *funcnode) {
 func (a *analysis) genStaticCall(caller *funcnode, site ssa.CallInstruction, call *ssa.CallCommon, result nodeid) {
 	fn := call.StaticCallee()
 
-	if pkg := fn.Pkg; pkg != nil {
-		if pkg.Pkg.Name() == "reflect" {
-			return
-		}
-		if pkg.Pkg.Name() == "runtime" {
+	sig := call.Signature()
+
+	// Resolve pointer-receiver methods per receiver object, if required.
+	if recv := sig.Recv(); recv != nil && a.selector.ReceiverSensitive() {
+		if _, ok := recv.Type().Underlying().(*types.Pointer); ok {
+			a.genReceiverCall(caller, site, call, fn, result)
 			return
 		}
 	}
 
 	// Called function object
-	var obj nodeid
-
-	// Find related context, if exists.
-	// or create a new function object with context generated
-	new_context := caller.func_context.GenContext(site)
-	if _, ok := a.csfuncobj[fn]; !ok {
-		a.csfuncobj[fn] = make(map[context]nodeid, 0)
-	}
-	newly_add := false
-	if selectiveContextPolicy(fn) {
-		if func_obj, ok2 := a.csfuncobj[fn][new_context]; ok2 {
-			obj = func_obj
-		} else {
-			obj = a.makeFunctionObject(fn)
-			a.csfuncobj[fn][new_context] = obj
-			newly_add = true
-		}
-	} else {
-		if func_obj, ok2 := a.csfuncobj[fn][NewContext()]; ok2 {
-			obj = func_obj
-		} else {
-			obj = a.makeFunctionObject(fn)
-			a.csfuncobj[fn][NewContext()] = obj
-			newly_add = true
-		}
-	}
+	obj := a.funcObject(&Invocation{Caller: caller.fn, Context: caller.func_context, Site: site, Callee: fn})
 
-	if newly_add {
-		new_funcnode := &funcnode{fn, obj, new_context}
-
-		// Add newly added funcnode into reachable queue
-		a.reachable_queue = append(a.reachable_queue, new_funcnode)
-
-		// Set called function obj's obj field.
-		a.nodes[obj].obj.funcn = new_funcnode
-	}
-
-	a.addCallGraphEdge(caller.fn, site, fn)
-
-	sig := call.Signature()
+	a.addCallGraphEdge(caller, site, a.nodes[obj].obj.funcn)
 
 	// Copy receiver, if any.
 	params := a.funcParams(obj)
@@ -236,6 +213,34 @@ func (a *analysis) genStaticCall(caller *funcnode, site ssa.CallInstruction, cal
 
 }
 
+// for a statically dispatched method call resolved per receiver object.
+func (a *analysis) genReceiverCall(caller *funcnode, site ssa.CallInstruction, call *ssa.CallCommon, fn *ssa.Function, result nodeid) {
+
+	sig := call.Signature()
+
+	// Allocate a contiguous relay params/results block for this call.
+	block := a.nextNode()
+	p := a.addNodes(sig.Params(), "recv.params")
+	r := a.addNodes(sig.Results(), "recv.results")
+
+	// Copy the actual parameters (sans receiver) into the call's params block.
+	for i, n := 0, sig.Params().Len(); i < n; i++ {
+		sz := a.sizeof(sig.Params().At(i).Type())
+		a.addflow(p, a.valueNode(call.Args[i+1]), sz, call.Value)
+		p += nodeid(sz)
+	}
+	// Copy the call's results block to the actual results.
+	if result != 0 {
+		a.addflow(result, r, a.sizeof(sig.Results()), call.Value)
+	}
+
+	recv := a.valueNode(call.Args[0])
+	a.recordOrigin(block, callBlockOrigin{site}, uint32(a.nextNode()-block))
+	rule := &receiverRule{caller, site, fn, block, recv}
+	a.attachRule(recv, rule)
+	a.recvsites = append(a.recvsites, rule)
+}
+
 // for a dynamic function call, function pointer.
 func (a *analysis) genDynamicCall(caller *funcnode, site ssa.CallInstruction, call *ssa.CallCommon, result nodeid) {
 
@@ -257,7 +262,9 @@ func (a *analysis) genDynamicCall(caller *funcnode, site ssa.CallInstruction, ca
 		a.addflow(result, r, a.sizeof(sig.Results()), call.Value)
 	}
 
-	a.nodes[a.valueNode(call.Value)].fly_solve = append(a.nodes[a.valueNode(call.Value)].fly_solve, &fpRule{caller, site, block})
+	a.recordOrigin(block, callBlockOrigin{site}, uint32(a.nextNode()-block))
+	a.attachRule(a.valueNode(call.Value), &fpRule{caller, site, block, a.valueNode(call.Value)})
+	a.addDynSite(caller, site, a.valueNode(call.Value), block)
 }
 
 // for a dynamic method invocation, interface.
@@ -281,7 +288,9 @@ func (a *analysis) genInvoke(caller *funcnode, site ssa.CallInstruction, call *s
 		a.addflow(result, r, a.sizeof(sig.Results()), call.Value)
 	}
 
-	a.nodes[a.valueNode(call.Value)].fly_solve = append(a.nodes[a.valueNode(call.Value)].fly_solve, &invokeRule{caller, site, call.Method, block})
+	a.recordOrigin(block, callBlockOrigin{site}, uint32(a.nextNode()-block))
+	a.attachRule(a.valueNode(call.Value), &invokeRule{caller: caller, site: site, method: call.Method, params: block, value: a.valueNode(call.Value)})
+	a.addDynSite(caller, site, a.valueNode(call.Value), block)
 }
 
 // \for call instruction instr.
@@ -305,10 +314,6 @@ func (a *analysis) genCall(cfc *funcnode, instr ssa.CallInstruction) {
 	} else {
 		a.genDynamicCall(cfc, instr, call, result)
 	}
-
-	if a.log != nil {
-		fmt.Fprintf(a.log, "\t%s in %s", instr, cfc.fn.Name())
-	}
 }
 
 // genLoad generates constraints for result = *(ptr + val).
@@ -321,10 +326,11 @@ func (a *analysis) genLoad(dst nodeid, ptr nodeid, offset, sizeof uint32) {
 		return // non-pointerlike operation
 	}
 	if dst == 0 || ptr == 0 {
-		panic(fmt.Sprintf("ill-typed load dst=n%d src=n%d", dst, ptr))
+		a.invalid("ill-typed load dst=n%d src=n%d", dst, ptr)
+		return
 	}
 	for i := uint32(0); i < sizeof; i++ {
-		a.nodes[ptr].fly_solve = append(a.nodes[ptr].fly_solve, &loadRule{offset, dst})
+		a.attachRule(ptr, &loadRule{offset, dst})
 		offset++
 		dst++
 	}
@@ -339,7 +345,7 @@ func (a *analysis) genOffsetAddr(dst nodeid, ptr nodeid, offset uint32) {
 		//       to  dst = src
 		a.addflow(dst, ptr, 1, nil)
 	} else {
-		a.nodes[ptr].fly_solve = append(a.nodes[ptr].fly_solve, &offsetAddrRule{offset, dst})
+		a.attachRule(ptr, &offsetAddrRule{offset, dst, ptr})
 	}
 }
 
@@ -353,10 +359,11 @@ func (a *analysis) genStore(ptr nodeid, src nodeid, offset, sizeof uint32) {
 		return // non-pointerlike operation
 	}
 	if src == 0 || ptr == 0 {
-		panic(fmt.Sprintf("ill-typed store dst=n%d src=n%d", ptr, src))
+		a.invalid("ill-typed store dst=n%d src=n%d", ptr, src)
+		return
 	}
 	for i := uint32(0); i < sizeof; i++ {
-		a.nodes[ptr].fly_solve = append(a.nodes[ptr].fly_solve, &storeRule{offset, src})
+		a.attachRule(ptr, &storeRule{offset, src})
 		offset++
 		src++
 	}
@@ -364,14 +371,7 @@ func (a *analysis) genStore(ptr nodeid, src nodeid, offset, sizeof uint32) {
 
 // genInstr generates constraints for instruction instr in context cfc.
 func (a *analysis) genInstr(cfc *funcnode, instr ssa.Instruction) {
-	if a.log != nil {
-		var prefix string
-		if val, ok := instr.(ssa.Value); ok {
-			prefix = val.Name() + " = "
-		}
-		fmt.Fprintf(a.log, "\t instr: %s%s\n", prefix, instr)
-	}
-
+	a.curinstr = instr
 	switch instr := instr.(type) {
 	case *ssa.DebugRef, *ssa.BinOp, *ssa.If, *ssa.Jump, *ssa.Range, *ssa.RunDefers:
 		// do nothing.
@@ -545,22 +545,33 @@ func (a *analysis) genInstr(cfc *funcnode, instr ssa.Instruction) {
 		a.addflow(a.panicNode, a.valueNode(instr.X), 1, nil)
 
 	default:
-		panic(fmt.Sprintf("unimplemented: %T", instr))
+		a.unsupported(cfc.fn, instr, "unimplemented: %T", instr)
 	}
 }
 
 // generates rules for function fn.
 func (a *analysis) genFunc(cfc *funcnode) {
 	fn := cfc.fn
+	a.curfn, a.curinstr = fn, nil
 
-	if a.log != nil {
-		fmt.Fprintln(a.log, "\tCreating nodes for local values of", cfc.func_context, cfc.fn.Name())
-	}
 	if fn.TypeParams().Len() > 0 && len(fn.TypeArgs()) == 0 {
+		a.diagnose(GenericBody, fn.Pos(), fn, "generic function %s not analyzed", fn)
+		return
+	}
+
+	// Modeled functions have no local values.
+	if impl := a.findIntrinsic(fn); impl != nil {
+		impl(a, cfc)
 		return
 	}
 
 	if strings.HasPrefix(fn.Synthetic, "instantiation wrapper ") {
+		a.diagnose(GenericBody, fn.Pos(), fn, "%s not analyzed", fn.Synthetic)
+		return
+	}
+
+	if fn.Blocks == nil {
+		a.genBodyless(cfc)
 		return
 	}
 
@@ -578,6 +589,8 @@ func (a *analysis) genFunc(cfc *funcnode) {
 
 	// Create value nodes for all value instructions
 	// since SSA may contain forward references.
+	// Those of copies come last, see substitution.go.
+	var copies []ssa.Value
 	for _, b := range fn.Blocks {
 		for _, instr := range b.Instrs {
 			switch instr := instr.(type) {
@@ -585,8 +598,12 @@ func (a *analysis) genFunc(cfc *funcnode) {
 			// value defined instr, but it doesnt matter, just do nothing
 
 			case ssa.Value:
+				if a.variableSubstitution() && a.isCopyValue(instr) {
+					copies = append(copies, instr)
+					continue
+				}
 				var comment string
-				if a.log != nil {
+				if a.tracer != nil {
 					comment = instr.Name()
 				}
 				id := a.addNodes(instr.Type(), comment)
@@ -594,6 +611,7 @@ func (a *analysis) genFunc(cfc *funcnode) {
 			}
 		}
 	}
+	a.genCopyNodes(cfc, copies)
 
 	// Generate constraints for each IR instructions.
 	for _, b := range fn.Blocks {
@@ -602,7 +620,8 @@ func (a *analysis) genFunc(cfc *funcnode) {
 		}
 	}
 
-	// clear buffer
+	// clear buffer, keeping the value nodes of this context for queries
+	cfc.localval = a.localval
 	a.localval = nil
 	a.localobj = nil
 }
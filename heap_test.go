@@ -0,0 +1,65 @@
+package pa
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const heapSample = `package main
+
+type X struct{ p *int }
+
+func NewX() *X  { return &X{} }
+func wrapX() *X { return NewX() }
+
+func direct1(x *X)  {}
+func direct2(x *X)  {}
+func wrapped1(x *X) {}
+func wrapped2(x *X) {}
+
+func main() {
+	direct1(NewX())
+	direct2(NewX())
+	wrapped1(wrapX())
+	wrapped2(wrapX())
+}
+`
+
+func TestHeapContexts(t *testing.T) {
+	pkg := buildSample(t, heapSample)
+	param := func(fn string) ssa.Value { return sampleParam(t, pkg, fn, "x") }
+	for _, test := range []struct {
+		k, heapK       int
+		direct         bool // whether direct1.x and direct2.x may alias
+		wrapped        bool // whether wrapped1.x and wrapped2.x may alias
+		wrappedHeapLen int  // the length of the heap context of the object of wrapped1.x
+	}{
+		{0, 0, true, true, 0},
+		{1, 0, false, true, 1},
+		{1, NoHeapCloning, true, true, 0},
+		{2, 0, false, false, 2},
+		{2, 1, false, true, 1},
+		{2, 2, false, false, 2},
+		{2, NoHeapCloning, true, true, 0},
+	} {
+		res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{K: test.k, HeapK: test.heapK})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.MayAlias(param("main.direct1"), param("main.direct2")); got != test.direct {
+			t.Errorf("K %d, HeapK %d: direct objects alias: %v, want %v", test.k, test.heapK, got, test.direct)
+		}
+		if got := res.MayAlias(param("main.wrapped1"), param("main.wrapped2")); got != test.wrapped {
+			t.Errorf("K %d, HeapK %d: wrapped objects alias: %v, want %v", test.k, test.heapK, got, test.wrapped)
+		}
+		pts := res.PointsTo(param("main.wrapped1"))
+		if len(pts) != 1 {
+			t.Errorf("K %d, HeapK %d: wrapped1.x points to %v, want one object", test.k, test.heapK, pts)
+			continue
+		}
+		if got := pts[0].HeapContext().Len(); got != test.wrappedHeapLen {
+			t.Errorf("K %d, HeapK %d: heap context %s, want %d elements", test.k, test.heapK, pts[0].HeapContext(), test.wrappedHeapLen)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package pa
+
+import (
+	"golang.org/x/tools/go/ssa"
+)
+
+// An intrinsic generates the rules of a function in place of its body,
+// for the functions whose effects are modeled rather than analyzed.
+// fc is the clone being generated; its params/results block is already allocated.
+type intrinsic func(a *analysis, fc *funcnode)
+
+// intrinsicsByName holds the modeled functions, keyed by ssa.Function.String().
+var intrinsicsByName = map[string]intrinsic{
+	// reflect
+	"reflect.New":                  extReflectNew,
+	"reflect.TypeOf":               extReflectTypeOf,
+	"reflect.ValueOf":              extReflectValueOf,
+	"(reflect.Value).Call":         extReflectValueCall,
+	"(reflect.Value).Elem":         extReflectValueElem,
+	"(reflect.Value).Field":        extReflectValueField,
+	"(reflect.Value).Interface":    extReflectValueInterface,
+	"(reflect.Value).Method":       extReflectValueMethod,
+	"(reflect.Value).MethodByName": extReflectValueMethod,
+	"(reflect.Value).Type":         extReflectValueType,
+	"(*reflect.rtype).Elem":        extReflectRtypeElem,
+
+	// runtime
+	"runtime.AddCleanup":    extRuntimeAddCleanup,
+	"runtime.Callers":       extNoEffect,
+	"runtime.CallersFrames": extRuntimeAlloc,
+	"runtime.FuncForPC":     extRuntimeAlloc,
+	"runtime.KeepAlive":     extNoEffect,
+	"runtime.SetFinalizer":  extRuntimeSetFinalizer,
+}
+
+// findIntrinsic returns the intrinsic of fn, or nil if its body is analyzed.
+// Summaries of Options.Summaries take precedence over the models here.
+// Instances of generic functions are modeled by their origin.
+// Every function of packages reflect and runtime is treated intrinsically:
+// those not modeled have no effect.
+func (a *analysis) findIntrinsic(fn *ssa.Function) intrinsic {
+	if s := a.summaryOf(fn); s != nil {
+		return s.summarize()
+	}
+	name := fn.String()
+	if orig := fn.Origin(); orig != nil {
+		name = orig.String()
+	}
+	impl := intrinsicsByName[name]
+	if impl == nil && (isReflect(fn) || isRuntime(fn)) {
+		a.diagnose(UnmodeledFunction, fn.Pos(), fn, "function %s has no model", fn)
+		impl = extNoEffect
+	}
+	return impl
+}
+
+func isReflect(fn *ssa.Function) bool {
+	return fn.Pkg != nil && fn.Pkg.Pkg.Path() == "reflect"
+}
+
+func isRuntime(fn *ssa.Function) bool {
+	return fn.Pkg != nil && fn.Pkg.Pkg.Path() == "runtime"
+}
+
+func extNoEffect(a *analysis, fc *funcnode) {}
+
+// addRule attaches r to node id, possibly while solving.
+// r alone is fired on the facts of pts(id) already propagated,
+// as the others saw them; it sees the rest with them.
+func (a *analysis) addRule(id nodeid, r rule) {
+	a.attachRule(id, r)
+	if n := a.nodes[id]; !n.prev_pts.set().IsEmpty() {
+		f := &ruleFire{rules: []rule{r}}
+		f.delta.Copy(&n.prev_pts.set().Sparse)
+		a.ruleFires = append(a.ruleFires, f)
+	}
+}
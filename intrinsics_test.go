@@ -0,0 +1,115 @@
+package pa
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// The stubs of the modeled packages, whose functions are intrinsics.
+const (
+	reflectStub = `package reflect
+
+type Type interface{ Elem() Type }
+
+type rtype struct{ size uintptr }
+
+func (t *rtype) Elem() Type
+
+type Value struct {
+	typ *rtype
+	ptr uintptr
+}
+
+func ValueOf(i interface{}) Value
+func TypeOf(i interface{}) Type
+func New(typ Type) Value
+func (v Value) Call(in []Value) []Value
+func (v Value) Elem() Value
+func (v Value) Field(i int) Value
+func (v Value) Interface() interface{}
+func (v Value) Method(i int) Value
+func (v Value) Type() Type
+`
+	runtimeStub = `package runtime
+
+func SetFinalizer(obj interface{}, finalizer interface{})
+func KeepAlive(x interface{})
+func Gosched()
+`
+)
+
+const intrinsicsSample = `package main
+
+import (
+	"reflect"
+	"runtime"
+)
+
+type T struct{ p *int }
+
+func (t *T) Get() *int { return t.p }
+
+func fin(t *T) {}
+
+func use(x *T, p, n, m *int, tt *T) {}
+
+func main() {
+	t := &T{p: new(int)}
+	v := reflect.ValueOf(t)
+	x := v.Interface().(*T)
+	p := v.Elem().Field(0).Interface().(*int)
+	n := reflect.New(reflect.TypeOf(0)).Interface().(*int)
+	m := v.Method(0).Call(nil)[0].Interface().(*int)
+	tt := reflect.New(v.Type().Elem()).Interface().(*T)
+	runtime.SetFinalizer(t, fin)
+	runtime.KeepAlive(t)
+	runtime.Gosched()
+	use(x, p, n, m, tt)
+}
+`
+
+func TestIntrinsics(t *testing.T) {
+	prog := buildSamples(t, map[string]string{
+		"reflect": reflectStub,
+		"runtime": runtimeStub,
+		"main":    intrinsicsSample,
+	})
+	pkg := prog.ImportedPackage("main")
+	res, err := Analyze(prog, nil, []*ssa.Package{pkg}, nil, &Options{K: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		fn, param string
+		want      []string
+	}{
+		{"main.use", "x", []string{"main.main:t0"}},
+		{"main.use", "p", []string{"main.main:t2"}},
+		{"main.use", "n", []string{"reflect.New[reflect.New(t12)]"}},
+		{"main.use", "m", []string{"main.main:t2"}}, // by Get
+		{"main.use", "tt", []string{"reflect.New[reflect.New(t23)]"}},
+		{"main.fin", "t", []string{"main.main:t0"}}, // called by the finalizer
+	} {
+		if got := labels(res, sampleParam(t, pkg, test.fn, test.param)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.%s points to %v, want %v", test.fn, test.param, got, test.want)
+		}
+	}
+
+	// The other functions of reflect and runtime have no effect.
+	unmodeled := make(map[string]bool)
+	for _, d := range res.Diagnostics {
+		if d.Kind == UnmodeledFunction {
+			unmodeled[d.Message] = true
+		}
+	}
+	want := map[string]bool{
+		"function runtime.Gosched has no model": true,
+		"function reflect.init has no model":    true,
+		"function runtime.init has no model":    true,
+	}
+	if !reflect.DeepEqual(unmodeled, want) {
+		t.Errorf("got unmodeled functions %v, want %v", unmodeled, want)
+	}
+}
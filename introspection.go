@@ -0,0 +1,133 @@
+package pa
+
+import (
+	"context"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// FuncMetrics are the metrics of a function measured by the
+// context-insensitive pre-analysis of the introspective mode.
+type FuncMetrics struct {
+	Func *ssa.Function
+
+	// PointsTo is the total size of the points-to sets
+	// of the local values of the function.
+	PointsTo int
+
+	// InFlow is the total size of the points-to sets of its parameters
+	// (including the receiver): the objects flowing in from callers.
+	InFlow int
+
+	// CallSites is the number of call sites calling the function.
+	CallSites int
+}
+
+// An IntrospectionHeuristic reports whether analyzing a function
+// context-sensitively is deemed profitable, given its metrics.
+type IntrospectionHeuristic func(m *FuncMetrics) bool
+
+// Introspective returns a heuristic in the style of introspective analysis:
+// a function is cloned unless the points-to volume of its locals exceeds maxPointsTo,
+// or its in-flow times its number of call sites exceeds maxInFlow.
+// Both thresholds bound the cost of cloning, as every clone duplicates the
+// locals, and callers with many distinct arguments create many clones.
+// A threshold of 0 means no limit.
+func Introspective(maxPointsTo, maxInFlow int) IntrospectionHeuristic {
+	return func(m *FuncMetrics) bool {
+		if maxPointsTo > 0 && m.PointsTo > maxPointsTo {
+			return false
+		}
+		if maxInFlow > 0 && m.InFlow*m.CallSites > maxInFlow {
+			return false
+		}
+		return true
+	}
+}
+
+// introspect runs the context-insensitive pre-analysis, and returns the
+// measured metrics, the options of the main analysis, whose ContextPolicy
+// clones only the functions accepted by opts.Introspection, and why the
+// pre-analysis was stopped, if it was.
+func introspect(ctx context.Context, prog_ *ssa.Program, paks []*ssa.Package, entry_funcs []*ssa.Function, opts *Options) (map[*ssa.Function]*FuncMetrics, *Options, string) {
+	// The options of the solver, which change only its cost, are kept.
+	pre := *opts
+	pre.K = 0
+	pre.ContextSelector = nil
+	pre.ContextPolicy = nil
+	pre.HeapK = 0
+	pre.MaxContextsPerFunc = 0
+	pre.Introspection = nil
+	pre.Provenance = false
+	pre.Tracer = nil
+	a := analyze(ctx, prog_, nil, paks, entry_funcs, &pre)
+	metrics := a.funcMetrics()
+
+	profitable := make(map[*ssa.Function]bool)
+	for fn, m := range metrics {
+		if opts.Introspection(m) {
+			profitable[fn] = true
+		}
+	}
+
+	refined := *opts
+	refined.Introspection = nil
+	policy := ContextPolicy(func(fn *ssa.Function) bool { return profitable[fn] })
+	if opts.ContextPolicy != nil {
+		policy = AllOf(opts.ContextPolicy, policy)
+	}
+	refined.ContextPolicy = policy
+	return metrics, &refined, a.incomplete
+}
+
+// funcMetrics measures the reachable functions of a solved analysis.
+func (a *analysis) funcMetrics() map[*ssa.Function]*FuncMetrics {
+	metrics := make(map[*ssa.Function]*FuncMetrics)
+	get := func(fn *ssa.Function) *FuncMetrics {
+		m, ok := metrics[fn]
+		if !ok {
+			m = &FuncMetrics{Func: fn}
+			metrics[fn] = m
+		}
+		return m
+	}
+
+	for v, objs := range a.csfuncobj {
+		fn, ok := v.(*ssa.Function)
+		if !ok {
+			continue
+		}
+		for _, obj := range objs {
+			fc := a.nodes[obj].obj.funcn
+			m := get(fn)
+			for v, id := range fc.localval {
+				pts := a.ptsSize(id, a.sizeof(v.Type()))
+				m.PointsTo += pts
+				if _, ok := v.(*ssa.Parameter); ok {
+					m.InFlow += pts
+				}
+			}
+		}
+	}
+
+	for _, sites := range a.callgraph {
+		for site, callees := range sites {
+			if site == nil {
+				continue // root
+			}
+			for callee := range callees {
+				get(callee).CallSites++
+			}
+		}
+	}
+	return metrics
+}
+
+// ptsSize returns the total size of the points-to sets of the n nodes from id.
+func (a *analysis) ptsSize(id nodeid, n uint32) int {
+	size := 0
+	for i := uint32(0); i < n; i++ {
+		size += a.nodes[id+nodeid(i)].pts.set().Len()
+	}
+	return size
+}
@@ -0,0 +1,103 @@
+package pa
+
+import (
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// A Label denotes an abstract object a pointer may point to:
+// an allocation site, analyzed in the context of the funcnode that allocated it.
+//
+// A Label may also denote a sub-element of an object,
+// e.g. the field of a struct whose address was taken.
+type Label struct {
+	obj *object    // the object block containing the node pointed to
+	typ types.Type // type of the node pointed to
+	id  nodeid     // the node pointed to
+}
+
+// label returns the Label for the node id found in some pts.
+func (a *analysis) label(id nodeid) *Label {
+	return &Label{obj: a.nodes[a.enclosingObject(id)].obj, typ: a.nodes[id].typ, id: id}
+}
+
+// enclosingObject returns the start node of the object block containing id.
+// Object blocks are contiguous and only their first node carries the object.
+func (a *analysis) enclosingObject(id nodeid) nodeid {
+	for a.nodes[id].obj == nil {
+		id--
+	}
+	return id
+}
+
+// Value returns the allocation site of the object, e.g. an *ssa.Alloc,
+// *ssa.MakeInterface or *ssa.Function; or nil if unknown.
+func (l *Label) Value() ssa.Value {
+	v, _ := l.obj.data.(ssa.Value)
+	return v
+}
+
+// Type returns the type of the object (or of the sub-element) pointed to.
+// For objects pointed to by interfaces, it is the dynamic type.
+func (l *Label) Type() types.Type {
+	return l.typ
+}
+
+// Func returns the function that allocated the object,
+// or nil for global objects.
+func (l *Label) Func() *ssa.Function {
+	if l.obj.funcn == nil {
+		return nil
+	}
+	return l.obj.funcn.fn
+}
+
+// Context returns the context of the funcnode that allocated the object,
+// the first one if the object is shared by clones; see HeapContext.
+// It is empty for global objects.
+func (l *Label) Context() Context {
+	return contextOf(l.obj.funcn)
+}
+
+// HeapContext returns the heap context of the object, which distinguishes
+// the objects allocated at the same site; see Options.HeapK.
+// It is empty for global objects.
+func (l *Label) HeapContext() Context {
+	return l.obj.hctx
+}
+
+// Pos returns the position of the allocation site, if known.
+func (l *Label) Pos() token.Pos {
+	if v := l.Value(); v != nil {
+		return v.Pos()
+	}
+	return token.NoPos
+}
+
+// String returns a human-readable description of the object,
+// e.g. "main.f:t0[g(t1)]".
+func (l *Label) String() string {
+	var s string
+	switch v := l.obj.data.(type) {
+	case *ssa.Function, *ssa.Global:
+		s = v.(ssa.Value).String()
+	case ssa.Value:
+		s = v.Name()
+		if fn := v.Parent(); fn != nil {
+			s = fn.String() + ":" + s
+		}
+	case types.Type:
+		s = "rtype(" + v.String() + ")"
+	default:
+		s = "object"
+		if fn := l.Func(); fn != nil {
+			s = fn.String() // e.g. reflect.New
+		}
+	}
+	if l.obj.hctx.Len() > 0 {
+		s += l.obj.hctx.String()
+	}
+	return s
+}
@@ -0,0 +1,362 @@
+package pa
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// A modelEntry is an entry of a model file.
+type modelEntry struct {
+	Func           string   `json:"func"`
+	ResultPointsTo string   `json:"result_points_to"`
+	FreshResult    bool     `json:"fresh_result"`
+	Result         string   `json:"result"`
+	Calls          string   `json:"calls"`
+	CallArgs       []string `json:"call_args"`
+	CallResults    []string `json:"call_results"`
+}
+
+// LoadModels reads the model files with the given names, see ParseModels.
+func LoadModels(prog *ssa.Program, filenames ...string) (map[*ssa.Function]*Summary, error) {
+	summaries, _, err := loadModels(prog, filenames...)
+	return summaries, err
+}
+
+// loadModels is LoadModels, also returning the names of the functions
+// of the entries in no package of prog.
+func loadModels(prog *ssa.Program, filenames ...string) (map[*ssa.Function]*Summary, []string, error) {
+	summaries := make(map[*ssa.Function]*Summary)
+	var unmatched []string
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		s, u, err := parseModels(prog, f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		for fn, summary := range s {
+			summaries[fn] = mergeSummaries(summaries[fn], summary)
+		}
+		unmatched = append(unmatched, u...)
+	}
+	return summaries, unmatched, nil
+}
+
+// ParseModels reads a model file and returns the summaries
+// of the functions of prog it declares.
+//
+// A model file declares summaries of functions in JSON, e.g.
+//
+//	[
+//		{"func": "sync.(*Once).Do", "calls": "arg1"},
+//		{"func": "bytes.TrimSpace", "result_points_to": "arg0"},
+//		{"func": "bytes.NewBuffer", "fresh_result": true},
+//		{"func": "sort.Slice", "calls": "arg1"},
+//		{"func": "strings.Map", "calls": "arg0"},
+//		{"func": "net/http.HandlerFunc.ServeHTTP", "calls": "arg0", "call_args": ["arg1", "arg2"]},
+//		{"func": "example.com/vendor/metrics.Emit"}
+//	]
+//
+// A function is named by its package path followed by its name, or by its
+// receiver type and name for methods, as "path.(*T).M", "path.(T).M" or "path.T.M";
+// the form of ssa.Function.String(), "(*path.T).M", is also accepted.
+// The package is the one of prog with the longest path prefixing the name,
+// so that paths may contain dots, as in "gopkg.in/yaml.v3.Marshal".
+// Arguments are "arg0", "arg1"... (the receiver of a method being arg0),
+// results "result0", "result1"...
+//
+// An entry declares the effects, as in a Summary:
+//
+//	result_points_to  "argN": result (by default result0) may point to whatever argN points to,
+//	                  argN being assignable to it or an interface
+//	fresh_result      true: result (by default result0) points to a new object
+//	result            "resultN": the result of result_points_to and fresh_result
+//	calls             "argN": argN is called, with the arguments call_args
+//	call_args         ["argN", ...]
+//	call_results      ["resultN", ...]: the results the results of the call flow to
+//
+// An entry without effects stubs the function out. Several entries for
+// the same function add up. The operands are checked against the signature
+// of the function, and those pointers flow between against each other, as
+// for a Summary: an entry making a *bytes.Buffer point to a []byte is an error.
+// Entries in no package of the program are ignored; those of
+// Options.ModelFiles are reported in Result.Diagnostics.
+func ParseModels(prog *ssa.Program, r io.Reader) (map[*ssa.Function]*Summary, error) {
+	summaries, _, err := parseModels(prog, r)
+	return summaries, err
+}
+
+// parseModels is ParseModels, also returning the names of the functions
+// of the entries in no package of prog.
+func parseModels(prog *ssa.Program, r io.Reader) (map[*ssa.Function]*Summary, []string, error) {
+	var entries []modelEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, nil, err
+	}
+
+	summaries := make(map[*ssa.Function]*Summary)
+	var unmatched []string
+	for i, e := range entries {
+		fn, err := lookupModelFunc(prog, e.Func)
+		if err != nil {
+			return nil, nil, fmt.Errorf("model %d: %v", i, err)
+		}
+		if fn == nil {
+			unmatched = append(unmatched, e.Func)
+			continue
+		}
+		summary, err := e.summary()
+		if err == nil {
+			err = checkModel(fn, summary)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("model %d (%s): %v", i, e.Func, err)
+		}
+		summaries[fn] = mergeSummaries(summaries[fn], summary)
+	}
+	return summaries, unmatched, nil
+}
+
+func mergeSummaries(x, y *Summary) *Summary {
+	if x == nil {
+		return y
+	}
+	return &Summary{Effects: append(x.Effects[:len(x.Effects):len(x.Effects)], y.Effects...)}
+}
+
+// summary returns the summary declared by e.
+func (e *modelEntry) summary() (*Summary, error) {
+	s := new(Summary)
+	result := 0
+	if e.Result != "" {
+		var err error
+		if result, err = modelOperand(e.Result, "result"); err != nil {
+			return nil, err
+		}
+	}
+	if e.ResultPointsTo != "" {
+		arg, err := modelOperand(e.ResultPointsTo, "arg")
+		if err != nil {
+			return nil, err
+		}
+		s.Effects = append(s.Effects, ResultAliases{Result: result, Arg: arg})
+	}
+	if e.FreshResult {
+		s.Effects = append(s.Effects, FreshResult{Result: result})
+	}
+	if e.Calls != "" {
+		callee, err := modelOperand(e.Calls, "arg")
+		if err != nil {
+			return nil, err
+		}
+		c := Calls{Func: callee}
+		for _, x := range e.CallArgs {
+			arg, err := modelOperand(x, "arg")
+			if err != nil {
+				return nil, err
+			}
+			c.Args = append(c.Args, arg)
+		}
+		for _, x := range e.CallResults {
+			res, err := modelOperand(x, "result")
+			if err != nil {
+				return nil, err
+			}
+			c.Results = append(c.Results, res)
+		}
+		s.Effects = append(s.Effects, c)
+	} else if len(e.CallArgs) > 0 || len(e.CallResults) > 0 {
+		return nil, fmt.Errorf("call_args or call_results without calls")
+	}
+	return s, nil
+}
+
+// modelOperand returns N for an operand "<kind>N".
+func modelOperand(x, kind string) (int, error) {
+	if strings.HasPrefix(x, kind) {
+		if n, err := strconv.Atoi(x[len(kind):]); err == nil && n >= 0 {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("bad operand %q, want %sN", x, kind)
+}
+
+// checkModel checks the operands of the effects of s against the signature of fn.
+func checkModel(fn *ssa.Function, s *Summary) error {
+	params, results := summaryParams(fn.Signature), fn.Signature.Results()
+	checkArg := func(i int) error {
+		if i >= len(params) {
+			return fmt.Errorf("no arg%d: %s has %d arguments", i, fn, len(params))
+		}
+		return nil
+	}
+	checkResult := func(i int) error {
+		if i >= results.Len() {
+			return fmt.Errorf("no result%d: %s has %d results", i, fn, results.Len())
+		}
+		return nil
+	}
+	for _, e := range s.Effects {
+		switch e := e.(type) {
+		case ResultAliases:
+			if err := checkResult(e.Result); err != nil {
+				return err
+			}
+			if err := checkArg(e.Arg); err != nil {
+				return err
+			}
+			if tRes, tArg := results.At(e.Result).Type(), params[e.Arg]; !summaryAssignable(tRes, tArg) {
+				return fmt.Errorf("arg%d of %s, of type %s, cannot flow to result%d, of type %s", e.Arg, fn, tArg, e.Result, tRes)
+			}
+		case FreshResult:
+			if err := checkResult(e.Result); err != nil {
+				return err
+			}
+			switch results.At(e.Result).Type().Underlying().(type) {
+			case *types.Pointer, *types.Slice:
+			default:
+				return fmt.Errorf("result%d of %s is not a pointer or a slice", e.Result, fn)
+			}
+		case Calls:
+			if err := checkArg(e.Func); err != nil {
+				return err
+			}
+			sig, ok := params[e.Func].Underlying().(*types.Signature)
+			if !ok {
+				return fmt.Errorf("arg%d of %s is not a func value", e.Func, fn)
+			}
+			if len(e.Args) > sig.Params().Len() {
+				return fmt.Errorf("call_args: arg%d of %s takes %d arguments", e.Func, fn, sig.Params().Len())
+			}
+			for i, arg := range e.Args {
+				if err := checkArg(arg); err != nil {
+					return err
+				}
+				if tParam := sig.Params().At(i).Type(); !summaryAssignable(tParam, params[arg]) {
+					return fmt.Errorf("call_args: arg%d of %s, of type %s, cannot be passed as a %s", arg, fn, params[arg], tParam)
+				}
+			}
+			if len(e.Results) > sig.Results().Len() {
+				return fmt.Errorf("call_results: arg%d of %s has %d results", e.Func, fn, sig.Results().Len())
+			}
+			for i, res := range e.Results {
+				if err := checkResult(res); err != nil {
+					return err
+				}
+				if tRes, tCall := results.At(res).Type(), sig.Results().At(i).Type(); !summaryAssignable(tRes, tCall) {
+					return fmt.Errorf("call_results: result%d of the call, of type %s, cannot flow to result%d, of type %s", i, tCall, res, tRes)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// lookupModelFunc returns the function of prog named name,
+// or nil if it is in no package of prog.
+func lookupModelFunc(prog *ssa.Program, name string) (*ssa.Function, error) {
+	pkg, recv, ptr, fname, ok := parseModelFunc(prog, name)
+	if !ok {
+		return nil, fmt.Errorf("bad function name %q", name)
+	}
+	if pkg == nil {
+		return nil, nil
+	}
+	path := pkg.Pkg.Path()
+
+	if recv == "" {
+		if fn := pkg.Func(fname); fn != nil {
+			return fn, nil
+		}
+		return nil, fmt.Errorf("no function %s", name)
+	}
+
+	t := pkg.Type(recv)
+	if t == nil {
+		return nil, fmt.Errorf("no type %s.%s", path, recv)
+	}
+	var T types.Type = t.Type()
+	if ptr {
+		T = types.NewPointer(T)
+	}
+	if sel := prog.MethodSets.MethodSet(T).Lookup(pkg.Pkg, fname); sel != nil {
+		if fn := prog.MethodValue(sel); fn != nil {
+			return fn, nil
+		}
+	}
+	return nil, fmt.Errorf("no method %s", name)
+}
+
+// parseModelFunc splits a function name of a model file into its package
+// in prog, nil if none, receiver type name, pointer receiver and name.
+func parseModelFunc(prog *ssa.Program, name string) (pkg *ssa.Package, recv string, ptr bool, fname string, ok bool) {
+	// (*path.T).M, (path.T).M
+	if strings.HasPrefix(name, "(") {
+		end := strings.Index(name, ").")
+		if end < 0 {
+			return
+		}
+		recv, fname = name[1:end], name[end+2:]
+		if strings.HasPrefix(recv, "*") {
+			ptr, recv = true, recv[1:]
+		}
+		dot := strings.LastIndex(recv, ".")
+		if dot < 0 {
+			return
+		}
+		path := recv[:dot]
+		recv = recv[dot+1:]
+		return modelPackage(prog, path), recv, ptr, fname, path != "" && recv != "" && fname != ""
+	}
+
+	// path.(*T).M, path.(T).M
+	if i := strings.Index(name, ".("); i >= 0 {
+		end := strings.Index(name[i:], ").")
+		if end < 0 {
+			return
+		}
+		path := name[:i]
+		recv, fname = name[i+2:i+end], name[i+end+2:]
+		if strings.HasPrefix(recv, "*") {
+			ptr, recv = true, recv[1:]
+		}
+		return modelPackage(prog, path), recv, ptr, fname, path != "" && recv != "" && fname != ""
+	}
+
+	// path.F, path.T.M: the path is the longest package path of prog prefixing name.
+	for _, p := range prog.AllPackages() {
+		path := p.Pkg.Path()
+		if strings.HasPrefix(name, path+".") && (pkg == nil || len(path) > len(pkg.Pkg.Path())) {
+			pkg = p
+		}
+	}
+	if pkg == nil {
+		return nil, "", false, "", strings.Contains(name, ".")
+	}
+	rest := name[len(pkg.Pkg.Path())+1:]
+	if i := strings.Index(rest, "."); i >= 0 {
+		recv, fname = rest[:i], rest[i+1:]
+		return pkg, recv, false, fname, recv != "" && fname != ""
+	}
+	return pkg, "", false, rest, rest != ""
+}
+
+// modelPackage returns the package of prog with the given path, or nil.
+func modelPackage(prog *ssa.Program, path string) *ssa.Package {
+	for _, p := range prog.AllPackages() {
+		if p.Pkg.Path() == path {
+			return p
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package pa
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const modelSample = `package main
+
+type Buffer struct {
+	buf []byte
+	off int
+}
+
+type T struct{ p *int }
+
+func (t *T) Get() *int { return t.p }
+
+func NewBuffer(b []byte) *Buffer
+func Trim(b []byte) []byte
+func Do(f func(*T) *int, t *T) *int
+func Count(n int) int
+
+func use(b *Buffer) {}
+
+func main() {
+	b := NewBuffer(make([]byte, 8))
+	use(b)
+	_ = b.off
+	_ = Trim(b.buf)
+	_ = Do((*T).Get, &T{})
+	_ = Count(1)
+}
+`
+
+func TestParseModels(t *testing.T) {
+	pkg := buildSample(t, modelSample)
+	for _, test := range []struct {
+		model string
+		funcs []string // the functions summarized
+		err   string
+	}{
+		{`[{"func": "main.Trim", "result_points_to": "arg0"}]`, []string{"main.Trim"}, ""},
+		{`[{"func": "main.NewBuffer", "fresh_result": true}]`, []string{"main.NewBuffer"}, ""},
+		{`[{"func": "main.Do", "calls": "arg0", "call_args": ["arg1"], "call_results": ["result0"]}]`, []string{"main.Do"}, ""},
+		{`[{"func": "main.(*T).Get"}, {"func": "(*main.T).Get", "fresh_result": true}]`, []string{"(*main.T).Get"}, ""},
+		{`[{"func": "main.Count"}, {"func": "example.com/other.F"}]`, []string{"main.Count"}, ""},
+
+		// The regression of the example of ParseModels, which made a
+		// *Buffer point to the array of a []byte.
+		{`[{"func": "main.NewBuffer", "result_points_to": "arg0"}]`, nil, "arg0 of main.NewBuffer, of type []byte, cannot flow to result0, of type *main.Buffer"},
+		{`[{"func": "main.Do", "calls": "arg0", "call_args": ["arg0"]}]`, nil, "cannot be passed as a *main.T"},
+		{`[{"func": "main.Trim", "calls": "arg0"}]`, nil, "arg0 of main.Trim is not a func value"},
+		{`[{"func": "main.Count", "fresh_result": true}]`, nil, "result0 of main.Count is not a pointer or a slice"},
+		{`[{"func": "main.Count", "result_points_to": "arg1"}]`, nil, "no arg1"},
+		{`[{"func": "main.Count", "result": "result1", "fresh_result": true}]`, nil, "no result1"},
+		{`[{"func": "main.Count", "result_points_to": "x0"}]`, nil, `bad operand "x0"`},
+		{`[{"func": "main.Count", "call_args": ["arg0"]}]`, nil, "call_args or call_results without calls"},
+		{`[{"func": "main.Missing"}]`, nil, "no function main.Missing"},
+		{`[{"func": "main.(*T).Set"}]`, nil, "no method main.(*T).Set"},
+	} {
+		summaries, err := ParseModels(pkg.Prog, strings.NewReader(test.model))
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: got error %v, want %q", test.model, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.model, err)
+			continue
+		}
+		var funcs []string
+		for fn := range summaries {
+			funcs = append(funcs, fn.String())
+		}
+		if !reflect.DeepEqual(funcs, test.funcs) {
+			t.Errorf("%s: got summaries of %v, want %v", test.model, funcs, test.funcs)
+		}
+	}
+}
+
+func TestModelFiles(t *testing.T) {
+	pkg := buildSample(t, modelSample)
+	file := filepath.Join(t.TempDir(), "models.json")
+	model := `[{"func": "main.NewBuffer", "fresh_result": true}, {"func": "example.com/other.F"}]`
+	if err := os.WriteFile(file, []byte(model), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{ModelFiles: []string{file}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := labels(res, sampleParam(t, pkg, "main.use", "b")), []string{"main.NewBuffer"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("b points to %v, want %v", got, want)
+	}
+	var unmatched []string
+	for _, d := range res.Diagnostics {
+		if d.Kind == UnmatchedModel {
+			unmatched = append(unmatched, d.Message)
+		}
+	}
+	if want := []string{"no package of the program has example.com/other.F"}; !reflect.DeepEqual(unmatched, want) {
+		t.Errorf("got unmatched models %q, want %q", unmatched, want)
+	}
+}
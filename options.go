@@ -0,0 +1,128 @@
+package pa
+
+import (
+	"golang.org/x/tools/go/ssa"
+)
+
+// Options configures the analysis.
+// A nil *Options passed to Analyze selects DefaultOptions.
+type Options struct {
+	// K is the depth of call strings (the k in k-CFA):
+	// each function is analyzed separately per string of its k most recent call sites.
+	// 0 makes the analysis context-insensitive.
+	// It is used when ContextSelector is nil.
+	K int
+
+	// ContextSelector chooses the contexts functions are analyzed under,
+	// e.g. CallSiteSensitive, ObjectSensitive or TypeSensitive.
+	// If nil, CallSiteSensitive{K} is used.
+	ContextSelector ContextSelector
+
+	// ContextPolicy selects the functions analyzed context-sensitively,
+	// e.g. AllOf(NotStd(), HasDynamicParams()).
+	// The others are analyzed once, in the empty context.
+	// If nil, every function is.
+	ContextPolicy ContextPolicy
+
+	// HeapK is the depth of heap contexts: an object allocated by a clone
+	// is distinguished by the HeapK most recent elements of the clone's context,
+	// so clones whose contexts agree on those share the object.
+	// 0, the default, gives objects the full context of the clone;
+	// NoHeapCloning makes the clones of a function share its objects.
+	HeapK int
+
+	// Introspection enables the two-phase introspective mode: a cheap
+	// context-insensitive pre-analysis first measures each function,
+	// then only the functions the heuristic accepts (and ContextPolicy allows)
+	// are analyzed context-sensitively. See Introspective.
+	Introspection IntrospectionHeuristic
+
+	// Summaries holds the summaries of the functions modeled instead of analyzed,
+	// e.g. library functions calling back into the program, or stubs of the
+	// packages not to analyze. A summary of a generic function applies to its instances.
+	Summaries map[*ssa.Function]*Summary
+
+	// ModelFiles names the model files declaring more summaries, see ParseModels.
+	// Summaries takes precedence over them.
+	ModelFiles []string
+
+	// ConservativeBodyless enables the conservative modeling of the functions
+	// without body (assembly, //go:linkname and cgo stubs) that have no summary:
+	// their results may point to any object of a compatible type,
+	// and their func-typed params are called.
+	// Otherwise they have no effect. See Result.Bodyless.
+	ConservativeBodyless bool
+
+	// SkipUnsupported makes the analysis skip the constructs it does not
+	// support, reporting them in Result.Diagnostics, instead of failing
+	// with an UnsupportedError.
+	SkipUnsupported bool
+
+	// CHAFallback resolves by class hierarchy analysis the dynamic call sites
+	// whose callee value points to nothing once solved, instead of leaving them
+	// without callees. Their edges are reported approximate; see Result.Approximate.
+	CHAFallback bool
+
+	// Tracer, if not nil, receives the events of the analysis, not including
+	// the pre-analysis of Introspection. Otherwise the log of Analyze,
+	// if not nil, receives them as text. See NewJSONTracer.
+	Tracer Tracer
+
+	// Provenance records why each points-to fact and call edge was found,
+	// for Result.ExplainPointsTo and Result.ExplainEdge, at some cost in memory.
+	Provenance bool
+
+	// CycleElimination collapses the cycles of flow edges found by the solver,
+	// without changing the results. It pays off when facts keep coming to
+	// cycles, e.g. objects discovered one after the other going around a
+	// ring of globals, and breaks about even otherwise; see BenchmarkCycles.
+	// It has no effect with Provenance.
+	CycleElimination bool
+
+	// NoVariableSubstitution gives each copy value, e.g. a Phi or a
+	// ChangeType, its own nodes, instead of sharing those of the values
+	// it is pointer-equivalent to. The results are the same either way; it
+	// is meant for measuring and debugging. Provenance disables it too.
+	NoVariableSubstitution bool
+
+	// Parallelism, if greater than 1, is the number of goroutines propagating
+	// points-to sets in the solver, by waves. The results are those of the
+	// sequential solver, except when a budget is exceeded. The solver stays
+	// sequential with Provenance, whose witnesses record the first copy of
+	// each fact, and with MaxContextsPerFunc, whose clones depend on the
+	// order in which contexts are discovered.
+	Parallelism int
+
+	// Worklist selects the order in which the solver takes the nodes to
+	// propagate from, see WorklistStrategy. The parallel solver takes them
+	// all at once in each wave, but applies their rules in this order.
+	Worklist WorklistStrategy
+
+	// SharedPointsToSets hash-conses the points-to sets of the solver:
+	// equal sets are stored once, and copied on write. It saves memory
+	// when many sets are equal, as those of clones at K >= 2, for some time.
+	SharedPointsToSets bool
+
+	// Budgets bound the work of the analysis; 0 means no limit.
+	// When MaxNodes or MaxIterations is exceeded, or the context passed to
+	// AnalyzeContext is done, the analysis stops and returns a partial
+	// result, flagged as Incomplete. With introspection, they apply to each phase.
+	MaxNodes      int // number of nodes of the constraint graph
+	MaxIterations int // number of solver iterations
+
+	// MaxContextsPerFunc bounds the clones of a function, the one in the
+	// empty context included: once the others fill the budget but one slot,
+	// the function is analyzed in the empty context, which is still sound.
+	// 1 analyzes every function once.
+	MaxContextsPerFunc int
+}
+
+// NoHeapCloning is the HeapK disabling heap cloning: the objects allocated
+// by the clones of a function are merged.
+const NoHeapCloning = -1
+
+// DefaultOptions returns the options used when none are given:
+// 1-call-site sensitivity.
+func DefaultOptions() *Options {
+	return &Options{K: 1}
+}
@@ -0,0 +1,315 @@
+package pa
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// The parallel solver proceeds by waves: it takes all the nodes of the
+// worklist at once, then Options.Parallelism workers compute their deltas,
+// propagate them along the flow edges and apply their concurrentRules,
+// locking the nodes they change. The other rules, which may generate
+// functions and clones, and cycle detection run sequentially after, in order.
+//
+// The solution is the least fixpoint of the same constraints, so the
+// points-to sets and the call graph are those of the sequential solver;
+// only the numbering of the nodes may differ. It is not used with
+// Options.MaxContextsPerFunc, whose clones depend on the order of discovery.
+
+const (
+	nodeLocks = 256 // the number of locks striping the nodes
+	waveChunk = 32  // the number of items of a wave a worker takes at once
+)
+
+// A waveItem is a node taken from the worklist in a wave.
+type waveItem struct {
+	id      nodeid
+	n       *node
+	delta   nodeset
+	flow_to []int  // the nodes the delta is propagated to
+	grew    []bool // whether the points-to set of each of them grew
+	fired   []rule // the rules of n, of which the concurrentRules were applied
+}
+
+// A concurrentRule only adds flow edges and points-to facts: the parallel
+// solver applies it concurrently with the others, through a waveWorker.
+type concurrentRule interface {
+	rule
+	addflowConcurrently(w *waveWorker, delta *nodeset)
+}
+
+// A waveWorker applies the changes of a chunk of a wave.
+type waveWorker struct {
+	a     *analysis
+	locks *[nodeLocks]sync.Mutex
+	space []int
+
+	// the results of the chunk, merged in order
+	grown        []nodeid // the nodes whose points-to set grew
+	propagations int
+	addedFlow    bool // whether a flow edge was added
+}
+
+// parallel reports whether the parallel solver is used. Provenance is
+// recorded by the sequential solver only, and MaxContextsPerFunc would
+// select other clones than the sequential solver.
+func (a *analysis) parallel() bool {
+	return a.opts.Parallelism > 1 && !a.opts.Provenance && a.opts.MaxContextsPerFunc == 0
+}
+
+// solveWaves runs the worklist iteration by waves.
+func (a *analysis) solveWaves() {
+	var locks [nodeLocks]sync.Mutex
+	p := newWorkerPool(a.opts.Parallelism)
+	defer p.close()
+
+	for {
+		// Take the wave, one item per node state.
+		var items []*waveItem
+		seen := make(map[*nodeState]bool)
+		a.fireQueued()
+		for {
+			id, ok := a.worklist.take()
+			if !ok {
+				break
+			}
+			a.stats.WorklistPops++
+			if n := a.nodes[id]; !seen[n.nodeState] {
+				seen[n.nodeState] = true
+				items = append(items, &waveItem{id: id, n: n})
+			}
+		}
+		a.iterations += len(items)
+		if a.budgetExceeded(true) {
+			break
+		}
+		if len(items) == 0 {
+			if a.flushConservative() || a.resolveEmptyReceivers() || a.resolveByCHA() {
+				continue
+			}
+			break // empty
+		}
+
+		// The deltas of all items, before anything changes.
+		chunks := (len(items) + waveChunk - 1) / waveChunk
+		p.run(chunks, func(c int) {
+			for _, it := range waveChunkOf(items, c) {
+				it.delta.Difference(&it.n.pts.set().Sparse, &it.n.prev_pts.set().Sparse)
+				if it.delta.IsEmpty() {
+					continue
+				}
+				it.n.prev_pts.assign(it.n.pts)
+				it.flow_to = it.n.flow_to.AppendTo(nil)
+				it.fired = it.n.fly_solve
+			}
+		})
+
+		// Their propagation, and their concurrent rules.
+		workers := make([]*waveWorker, chunks)
+		p.run(chunks, func(c int) {
+			w := &waveWorker{a: a, locks: &locks}
+			workers[c] = w
+			for _, it := range waveChunkOf(items, c) {
+				if it.delta.IsEmpty() {
+					continue
+				}
+				it.grew = make([]bool, len(it.flow_to))
+				for j, y := range it.flow_to {
+					it.grew[j] = w.addAll(nodeid(y), &it.delta)
+				}
+				w.propagations += len(it.flow_to)
+				for _, r := range it.fired {
+					if r, ok := r.(concurrentRule); ok {
+						r.addflowConcurrently(w, &it.delta)
+					}
+				}
+			}
+		})
+
+		// Then, sequentially and in order, the worklist, the cycle
+		// candidates and the other rules.
+		for _, w := range workers {
+			a.stats.Propagations += w.propagations
+			if w.addedFlow {
+				a.flowVersion++
+			}
+			for _, id := range w.grown {
+				a.addWork(id)
+			}
+		}
+		for _, it := range items {
+			if it.delta.IsEmpty() {
+				continue
+			}
+			for j, y := range it.flow_to {
+				if it.grew[j] {
+					a.addWork(nodeid(y))
+				}
+				if a.cycleElimination() && !it.grew[j] {
+					a.checkCycle(it.n, nodeid(y))
+				}
+			}
+			if a.tracer != nil {
+				a.tracer.PtsChanged(int(it.id), it.delta.AppendTo(nil))
+			}
+		}
+		for _, it := range items {
+			if it.delta.IsEmpty() {
+				continue
+			}
+			// The rules attached since, or brought by a merge, were not applied.
+			rules := it.n.fly_solve
+			prefix := len(it.fired) > 0 && len(rules) >= len(it.fired) && &rules[0] == &it.fired[0]
+			for i, r := range rules {
+				if _, ok := r.(concurrentRule); ok && prefix && i < len(it.fired) {
+					continue
+				}
+				r.addflow(a, &it.delta)
+			}
+		}
+		a.collapseCycles()
+	}
+}
+
+// waveChunkOf returns chunk c of items.
+func waveChunkOf(items []*waveItem, c int) []*waveItem {
+	items = items[c*waveChunk:]
+	if len(items) > waveChunk {
+		items = items[:waveChunk]
+	}
+	return items
+}
+
+// lock locks the stripes of the nodes x and y, in order.
+func (w *waveWorker) lock(x, y *node) (i, j nodeid) {
+	i, j = x.rep%nodeLocks, y.rep%nodeLocks
+	if i > j {
+		i, j = j, i
+	}
+	w.locks[i].Lock()
+	if j != i {
+		w.locks[j].Lock()
+	}
+	return i, j
+}
+
+func (w *waveWorker) unlock(i, j nodeid) {
+	if j != i {
+		w.locks[j].Unlock()
+	}
+	w.locks[i].Unlock()
+}
+
+// addAll adds delta to the points-to set of dst, and reports whether it grew.
+func (w *waveWorker) addAll(dst nodeid, delta *nodeset) bool {
+	mu := &w.locks[w.a.nodes[dst].rep%nodeLocks]
+	mu.Lock()
+	grew := w.a.nodes[dst].pts.addAll(delta)
+	mu.Unlock()
+	return grew
+}
+
+// add adds obj to the points-to set of dst, queuing dst if it grew.
+func (w *waveWorker) add(dst, obj nodeid) {
+	mu := &w.locks[w.a.nodes[dst].rep%nodeLocks]
+	mu.Lock()
+	grew := w.a.nodes[dst].pts.add(obj)
+	mu.Unlock()
+	if grew {
+		w.grown = append(w.grown, dst)
+	}
+}
+
+// addflow is auxaddflow for concurrentRules: it adds the flow edge src -> dst,
+// queuing dst if its points-to set grew.
+func (w *waveWorker) addflow(dst, src nodeid) {
+	if dst == src {
+		return
+	}
+	nsrc, ndst := w.a.nodes[src], w.a.nodes[dst]
+	i, j := w.lock(nsrc, ndst)
+	added := nsrc.flow_to.add(dst)
+	grew := added && ndst.pts.addAll(nsrc.pts.set())
+	w.unlock(i, j)
+	if added {
+		w.addedFlow = true
+	}
+	if grew {
+		w.grown = append(w.grown, dst)
+	}
+}
+
+func (c *loadRule) addflowConcurrently(w *waveWorker, delta *nodeset) {
+	w.space = delta.AppendTo(w.space[:0])
+	for _, x := range w.space {
+		w.addflow(c.d, nodeid(x)+nodeid(c.offset))
+	}
+}
+
+func (c *storeRule) addflowConcurrently(w *waveWorker, delta *nodeset) {
+	w.space = delta.AppendTo(w.space[:0])
+	for _, x := range w.space {
+		w.addflow(nodeid(x)+nodeid(c.offset), c.s)
+	}
+}
+
+func (c *offsetAddrRule) addflowConcurrently(w *waveWorker, delta *nodeset) {
+	w.space = delta.AppendTo(w.space[:0])
+	for _, x := range w.space {
+		w.add(c.d, nodeid(x)+nodeid(c.offset))
+	}
+}
+
+// A workerPool runs the chunks of the phases of the waves on its goroutines.
+type workerPool struct {
+	start []chan func()
+	wg    sync.WaitGroup
+}
+
+func newWorkerPool(n int) *workerPool {
+	p := &workerPool{start: make([]chan func(), n)}
+	for i := range p.start {
+		p.start[i] = make(chan func())
+		go func(start chan func()) {
+			for f := range start {
+				f()
+				p.wg.Done()
+			}
+		}(p.start[i])
+	}
+	return p
+}
+
+// run calls f(c) for each chunk c in [0, chunks), on the workers of p,
+// and waits for them. A single chunk is run by the caller.
+func (p *workerPool) run(chunks int, f func(c int)) {
+	if chunks == 1 {
+		f(0)
+		return
+	}
+	var next int64
+	task := func() {
+		for {
+			c := int(atomic.AddInt64(&next, 1) - 1)
+			if c >= chunks {
+				return
+			}
+			f(c)
+		}
+	}
+	workers := len(p.start)
+	if workers > chunks {
+		workers = chunks
+	}
+	p.wg.Add(workers)
+	for _, start := range p.start[:workers] {
+		start <- task
+	}
+	p.wg.Wait()
+}
+
+func (p *workerPool) close() {
+	for _, start := range p.start {
+		close(start)
+	}
+}
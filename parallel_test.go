@@ -0,0 +1,313 @@
+package pa
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// The sample programs, without imports.
+var parallelSamples = map[string]string{
+	"calls": `package main
+
+type I interface{ M() *int }
+type A struct{ p *int }
+type B struct{ q *int }
+
+func (a *A) M() *int { return a.p }
+func (b B) M() *int  { return b.q }
+
+func id(x *int) *int     { return x }
+func wrap(x *int) *int   { return id(x) }
+func apply(f func(*int) *int, x *int) *int { return f(x) }
+
+func main() {
+	x, y := new(int), new(int)
+	var is []I
+	is = append(is, &A{p: wrap(x)}, B{q: wrap(y)})
+	for _, i := range is {
+		println(apply(id, i.M()))
+	}
+	f := func(z *int) *int { return apply(wrap, z) }
+	println(f(x))
+}
+`,
+	"heap": `package main
+
+type node struct {
+	next *node
+	val  *int
+}
+
+func push(l *node, v *int) *node { return &node{next: l, val: v} }
+
+func last(l *node) *node {
+	for l.next != nil {
+		l = l.next
+	}
+	return l
+}
+
+func main() {
+	var l *node
+	for i := 0; i < 3; i++ {
+		l = push(l, new(int))
+	}
+	m := map[string]*node{"a": l}
+	ch := make(chan *node, 1)
+	ch <- last(m["a"])
+	println((<-ch).val)
+}
+`,
+	"synthetic": syntheticProgram(12),
+}
+
+// syntheticProgram returns a program of n types whose methods and closures
+// exchange their objects through shared globals.
+func syntheticProgram(n int) string {
+	var b strings.Builder
+	b.WriteString(`package main
+
+type I interface{ Get() I; Set(I); Apply(func(I) I) I }
+type Box struct{ a, b I; f func(I) I; next *Box }
+
+var all []I
+var boxes []*Box
+`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `
+type T%[1]d struct{ box *Box; v I; fs []func(I) I }
+
+func (t *T%[1]d) Get() I { if t.v != nil { return t.v }; return t.box.a }
+func (t *T%[1]d) Set(x I) { t.v = x; t.box.b = x; t.box.next = &Box{a: x, f: t.Apply2} }
+func (t *T%[1]d) Apply(f func(I) I) I { r := f(t.Get()); t.fs = append(t.fs, f); return r }
+func (t *T%[1]d) Apply2(x I) I { t.Set(x); return t.box.next.a }
+
+func mk%[1]d(x I) I {
+	b := &Box{a: x}
+	t := &T%[1]d{box: b}
+	b.f = func(y I) I { t.Set(y); return t.Get() }
+	boxes = append(boxes, b)
+	all = append(all, t)
+	return t
+}
+`, i)
+	}
+	b.WriteString("\nfunc route(x I, k int) I {\n\tswitch k {\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "\tcase %d:\n\t\treturn mk%d(x)\n", i, i)
+	}
+	fmt.Fprintf(&b, `	}
+	return x
+}
+
+func pass(x I, f func(I) I) I { return f(x) }
+
+func main() {
+	var x I = route(nil, 0)
+	for k := 0; k < %d; k++ {
+		y := route(x, k)
+		y.Set(x)
+		x = y.Apply(func(z I) I { return pass(z, func(w I) I { return w.Get() }) })
+		for _, b := range boxes {
+			x = pass(x, b.f)
+			if b.next != nil {
+				x = b.next.a
+			}
+		}
+		for _, a := range all {
+			a.Set(x)
+			x = a.Get()
+		}
+	}
+	_ = x
+}
+`, n)
+	return b.String()
+}
+
+// buildSample builds the SSA of the program src.
+func buildSample(t testing.TB, src string) *ssa.Package {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "main.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, _, err := ssautil.BuildPackage(&types.Config{}, fset, types.NewPackage("main", ""), []*ast.File{f}, ssa.InstantiateGenerics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+// buildSamples builds the SSA of the program of the packages srcs, keyed
+// by import path, which import only each other.
+func buildSamples(t testing.TB, srcs map[string]string) *ssa.Program {
+	fset := token.NewFileSet()
+	files := make(map[string]*ast.File)
+	for path, src := range srcs {
+		f, err := parser.ParseFile(fset, path+".go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[path] = f
+	}
+
+	prog := ssa.NewProgram(fset, ssa.InstantiateGenerics)
+	pkgs := make(map[string]*types.Package)
+	var check func(path string) (*types.Package, error)
+	conf := &types.Config{Importer: importerFunc(func(path string) (*types.Package, error) { return check(path) })}
+	check = func(path string) (*types.Package, error) {
+		if pkg, ok := pkgs[path]; ok {
+			return pkg, nil
+		}
+		f, ok := files[path]
+		if !ok {
+			return nil, fmt.Errorf("no package %s", path)
+		}
+		info := &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Instances:  make(map[*ast.Ident]types.Instance),
+			Scopes:     make(map[ast.Node]*types.Scope),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		}
+		pkg, err := conf.Check(path, fset, []*ast.File{f}, info)
+		if err != nil {
+			return nil, err
+		}
+		pkgs[path] = pkg
+		prog.CreatePackage(pkg, []*ast.File{f}, info, true)
+		return pkg, nil
+	}
+	for path := range srcs {
+		if _, err := check(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prog.Build()
+	return prog
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// solution returns the call edges and the points-to sets of the values
+// of pkg, as sorted strings: the node numbering may differ between solvers.
+func solution(t testing.TB, pkg *ssa.Package, opts *Options) []string {
+	res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	callgraph.GraphVisitEdges(res.CallGraph, func(e *callgraph.Edge) error {
+		out = append(out, fmt.Sprintf("edge %s -> %s at %s", e.Caller.Func, e.Callee.Func, pkg.Prog.Fset.Position(e.Pos())))
+		return nil
+	})
+	for fn := range ssautil.AllFunctions(pkg.Prog) {
+		if fn.Pkg != pkg {
+			continue
+		}
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				v, ok := instr.(ssa.Value)
+				if !ok {
+					continue
+				}
+				var labels []string
+				for _, l := range res.PointsTo(v) {
+					labels = append(labels, l.String())
+				}
+				sort.Strings(labels)
+				out = append(out, fmt.Sprintf("pts %s:%s %v", fn, v.Name(), labels))
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// sampleFunc returns the function of pkg's program named name,
+// as printed by ssa.Function.String, e.g. "main.f" or "(*main.T).M".
+func sampleFunc(t testing.TB, pkg *ssa.Package, name string) *ssa.Function {
+	for fn := range ssautil.AllFunctions(pkg.Prog) {
+		if fn.String() == name {
+			return fn
+		}
+	}
+	t.Fatalf("no function %s", name)
+	return nil
+}
+
+// sampleParam returns the parameter name of the function fn of pkg.
+func sampleParam(t testing.TB, pkg *ssa.Package, fn, name string) *ssa.Parameter {
+	for _, p := range sampleFunc(t, pkg, fn).Params {
+		if p.Name() == name {
+			return p
+		}
+	}
+	t.Fatalf("no parameter %s of %s", name, fn)
+	return nil
+}
+
+// labels returns the sorted labels of the objects v may point to.
+func labels(res *Result, v ssa.Value) []string {
+	out := []string{}
+	for _, l := range res.PointsTo(v) {
+		out = append(out, l.String())
+	}
+	sort.Strings(out)
+	return out
+}
+
+// TestParallelSolver checks that the parallel solver finds the results of
+// the sequential one. Run it with -race.
+func TestParallelSolver(t *testing.T) {
+	configs := map[string]func() *Options{
+		"insensitive": func() *Options { return &Options{} },
+		"1-cfa":       func() *Options { return &Options{K: 1} },
+		"2-cfa":       func() *Options { return &Options{K: 2, CycleElimination: true} },
+		"2-obj":       func() *Options { return &Options{K: 2, ContextSelector: ObjectSensitive{K: 2}} },
+		"shared":      func() *Options { return &Options{K: 1, SharedPointsToSets: true, Worklist: WorklistLRF} },
+	}
+	for name, src := range parallelSamples {
+		pkg := buildSample(t, src)
+		for cname, config := range configs {
+			want := solution(t, pkg, config())
+			for _, n := range []int{2, 4, 16} {
+				opts := config()
+				opts.Parallelism = n
+				if got := solution(t, pkg, opts); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s, %s, Parallelism %d: got\n%s\nwant\n%s", name, cname, n,
+						strings.Join(got, "\n"), strings.Join(want, "\n"))
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkSolve(b *testing.B) {
+	pkg := buildSample(b, syntheticProgram(60))
+	for _, n := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("Parallelism=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{K: 2, Parallelism: n}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -26,6 +26,9 @@ type object struct {
 
 	// the func containing this objects with context-sensitivity applied
 	funcn *funcnode
+
+	// heap context of this object, derived from the context of funcn
+	hctx Context
 }
 
 type nodeset struct {
@@ -52,10 +55,7 @@ type node struct {
 
 	sub_element *subEleInfo
 
-	fly_solve []rule  // on-the-fly-solved rule attached to this node
-	flow_to   nodeset // all pointer-like valuenode it may flow to
-	pts       nodeset // pt(n)
-	prev_pts  nodeset // pt(n) in previous iteration, for difference propagation
+	*nodeState // shared by the nodes of a collapsed cycle
 }
 
 // A subEleInfo describes one subelement (node) of the flattening-out
@@ -92,10 +92,11 @@ func (a *analysis) addNodes(typ types.Type, comment string) nodeid {
 // addOneNode creates a single node with type typ, and returns its id.
 func (a *analysis) addOneNode(typ types.Type, comment string, subelement *subEleInfo) nodeid {
 	id := a.nextNode()
-	a.nodes = append(a.nodes, &node{typ: typ, sub_element: subelement, fly_solve: make([]rule, 0)})
-	if a.log != nil {
-		fmt.Fprintf(a.log, "\tcreate n%d %s for %s%s\n",
-			id, typ, comment)
+	a.nodes = append(a.nodes, &node{typ: typ, sub_element: subelement, nodeState: &nodeState{
+		rep: id, fly_solve: make([]rule, 0), pts: a.newPtset(), prev_pts: a.newPtset(),
+	}})
+	if a.tracer != nil {
+		a.tracer.NodeCreated(int(id), typ, comment)
 	}
 	return id
 }
@@ -108,9 +109,7 @@ func (a *analysis) setValueNode(v ssa.Value, id nodeid, cfc *funcnode) {
 	} else {
 		a.globalval[v] = id
 	}
-	if a.log != nil {
-		fmt.Fprintf(a.log, "\tval[%s] = n%d  (%T)\n", v.Name(), id, v)
-	}
+	a.recordOrigin(id, v, a.sizeof(v.Type()))
 }
 
 // endObject denotes a single object allocation.
@@ -132,9 +131,50 @@ func (a *analysis) endObject(obj nodeid, func_node *funcnode, data interface{})
 	}
 	objNode.obj = o
 
+	if func_node != nil {
+		o.hctx = a.heapContext(func_node)
+		if v, ok := data.(ssa.Value); ok && a.opts.HeapK != 0 {
+			if _, ok := a.heapobj[v]; !ok {
+				a.heapobj[v] = make(map[Context]nodeid)
+			}
+			a.heapobj[v][o.hctx] = obj
+		}
+	}
+
 	return o
 }
 
+// heapContext returns the heap context of the objects allocated by func_node:
+// the HeapK most recent elements of its context; see Options.HeapK.
+func (a *analysis) heapContext(func_node *funcnode) Context {
+	k := a.opts.HeapK
+	switch {
+	case k == 0:
+		return func_node.func_context
+	case k < 0: // NoHeapCloning
+		return NewContext()
+	}
+	elems := func_node.func_context.Elements()
+	if len(elems) <= k {
+		return func_node.func_context
+	}
+	hctx := NewContext()
+	for _, e := range elems[len(elems)-k:] {
+		hctx = a.pushContext(hctx, e, k)
+	}
+	return hctx
+}
+
+// heapObject returns the object allocated at v by another clone of the
+// function of func_node with the same heap context, or 0 if none.
+// Objects are shared among clones only if heap contexts are shorter than contexts.
+func (a *analysis) heapObject(func_node *funcnode, v ssa.Value) nodeid {
+	if a.opts.HeapK == 0 {
+		return 0
+	}
+	return a.heapobj[v][a.heapContext(func_node)]
+}
+
 // creates a object pointed by a interface var
 func (a *analysis) makeInterfaceObj(typ types.Type, func_node *funcnode, data interface{}) nodeid {
 	obj := a.addOneNode(typ, "tagged.T", nil)
@@ -156,7 +196,7 @@ func (a *analysis) valueNode(v ssa.Value) nodeid {
 	id, ok := a.globalval[v]
 	if !ok {
 		var comment string
-		if a.log != nil {
+		if a.tracer != nil {
 			comment = v.String()
 		}
 		id = a.addNodes(v.Type(), comment)
@@ -168,12 +208,12 @@ func (a *analysis) valueNode(v ssa.Value) nodeid {
 	} else {
 		if _, ok := v.(*ssa.FreeVar); ok {
 			//a.globalflushbuf.add(id)
-			a.nodes[id].prev_pts.Clear()
+			a.nodes[id].prev_pts.clear()
 			a.worklist.add(nodeid(id))
 		}
 		if _, ok := v.(*ssa.Global); ok {
 			//a.globalflushbuf.add(id)
-			a.nodes[id].prev_pts.Clear()
+			a.nodes[id].prev_pts.clear()
 			a.worklist.add(nodeid(id))
 		}
 	}
@@ -199,9 +239,6 @@ func (a *analysis) objectNode(func_node *funcnode, v ssa.Value) nodeid {
 				// not addressable
 			}
 
-			if a.log != nil {
-				fmt.Fprintf(a.log, "\tglobalobj[%s] = n%d\n", v, obj)
-			}
 			a.globalobj[v] = obj
 		}
 		return obj
@@ -212,30 +249,40 @@ func (a *analysis) objectNode(func_node *funcnode, v ssa.Value) nodeid {
 	if !ok {
 		switch v := v.(type) {
 		case *ssa.Alloc:
-			obj = a.nextNode()
-			a.addNodes(mustDeref(v.Type()), "alloc")
-			a.endObject(obj, func_node, v)
+			if obj = a.heapObject(func_node, v); obj == 0 {
+				obj = a.nextNode()
+				a.addNodes(mustDeref(v.Type()), "alloc")
+				a.endObject(obj, func_node, v)
+			}
 
 		case *ssa.MakeSlice:
-			obj = a.nextNode()
-			a.addNodes(sliceToArray(v.Type()), "makeslice")
-			a.endObject(obj, func_node, v)
+			if obj = a.heapObject(func_node, v); obj == 0 {
+				obj = a.nextNode()
+				a.addNodes(sliceToArray(v.Type()), "makeslice")
+				a.endObject(obj, func_node, v)
+			}
 
 		case *ssa.MakeChan:
-			obj = a.nextNode()
-			a.addNodes(v.Type().Underlying().(*types.Chan).Elem(), "makechan")
-			a.endObject(obj, func_node, v)
+			if obj = a.heapObject(func_node, v); obj == 0 {
+				obj = a.nextNode()
+				a.addNodes(v.Type().Underlying().(*types.Chan).Elem(), "makechan")
+				a.endObject(obj, func_node, v)
+			}
 
 		case *ssa.MakeMap:
-			obj = a.nextNode()
-			tmap := v.Type().Underlying().(*types.Map)
-			a.addNodes(tmap.Key(), "makemap.key")
-			a.addNodes(tmap.Elem(), "makemap.value")
-			a.endObject(obj, func_node, v)
+			if obj = a.heapObject(func_node, v); obj == 0 {
+				obj = a.nextNode()
+				tmap := v.Type().Underlying().(*types.Map)
+				a.addNodes(tmap.Key(), "makemap.key")
+				a.addNodes(tmap.Elem(), "makemap.value")
+				a.endObject(obj, func_node, v)
+			}
 
 		case *ssa.MakeInterface:
 			tConc := v.X.Type()
-			obj = a.makeInterfaceObj(tConc, func_node, v)
+			if obj = a.heapObject(func_node, v); obj == 0 {
+				obj = a.makeInterfaceObj(tConc, func_node, v)
+			}
 
 			// Copy the value into it, if nontrivial.
 			if x := a.valueNode(v.X); x != 0 {
@@ -256,10 +303,6 @@ func (a *analysis) objectNode(func_node *funcnode, v ssa.Value) nodeid {
 			obj = a.objectNode(func_node, v.X)
 
 		}
-
-		if a.log != nil {
-			fmt.Fprintf(a.log, "\tlocalobj[%s] = n%d\n", v.Name(), obj)
-		}
 		a.localobj[v] = obj
 	}
 	return obj
@@ -270,7 +313,8 @@ func (a *analysis) objectNode(func_node *funcnode, v ssa.Value) nodeid {
 func (a *analysis) valueOffsetNode(v ssa.Value, index int) nodeid {
 	id := a.valueNode(v)
 	if id == 0 {
-		panic(fmt.Sprintf("cannot offset within n0: %s = %s", v.Name(), v))
+		a.invalid("cannot offset within n0: %s = %s", v.Name(), v)
+		return 0
 	}
 	return id + nodeid(a.offsetOf(v.Type(), index))
 }
@@ -280,11 +324,10 @@ func (a *analysis) valueOffsetNode(v ssa.Value, index int) nodeid {
 // Panic ensues if !isTaggedObject(id).
 func (a *analysis) taggedValue(obj nodeid) (tDyn types.Type, v nodeid, indirect bool) {
 	n := a.nodes[obj]
-	flags := n.obj.tags
-	if flags&otTagged == 0 {
-		panic(fmt.Sprintf("not a tagged object: n%d", obj))
+	if n.obj == nil || n.obj.tags&otTagged == 0 {
+		panic(invariantError(fmt.Sprintf("not a tagged object: n%d", obj)))
 	}
-	return n.typ, obj + 1, flags&8 != 0
+	return n.typ, obj + 1, n.obj.tags&8 != 0
 }
 
 // here, the id denotes the start of a function block.
@@ -293,7 +336,7 @@ func (a *analysis) taggedValue(obj nodeid) (tDyn types.Type, v nodeid, indirect
 func (a *analysis) funcParams(id nodeid) nodeid {
 	n := a.nodes[id]
 	if n.obj == nil || n.obj.tags&otFunction == 0 {
-		panic(fmt.Sprintf("funcParams(n%d): not a function object block", id))
+		panic(invariantError(fmt.Sprintf("funcParams(n%d): not a function object block", id)))
 	}
 	return id + 1
 }
@@ -302,7 +345,7 @@ func (a *analysis) funcParams(id nodeid) nodeid {
 func (a *analysis) funcResults(id nodeid) nodeid {
 	n := a.nodes[id]
 	if n.obj == nil || n.obj.tags&otFunction == 0 {
-		panic(fmt.Sprintf("funcResults(n%d): not a function object block", id))
+		panic(invariantError(fmt.Sprintf("funcResults(n%d): not a function object block", id)))
 	}
 	sig := n.typ.(*types.Signature)
 	id += 1 + nodeid(a.sizeof(sig.Params()))
@@ -318,12 +361,8 @@ func (a *analysis) funcResults(id nodeid) nodeid {
 
 // makeFunctionObject creates and returns a new function object with context (callstring).
 // related to a funcnode.
-// if we can find it in csfuncobj   map[ssa.Value]map[context]nodeid, there is no need to call addreachable
+// if we can find it in csfuncobj   map[ssa.Value]map[Context]nodeid, there is no need to call addreachable
 func (a *analysis) makeFunctionObject(fn *ssa.Function) nodeid {
-	if a.log != nil {
-		fmt.Fprintf(a.log, "\t---- makeFunctionObject %s\n", fn)
-	}
-
 	// obj is the function object (identity, params, results).
 	obj := a.nextNode()
 	//cgn := a.makeCGNode(fn, obj, callersite)
@@ -335,10 +374,7 @@ func (a *analysis) makeFunctionObject(fn *ssa.Function) nodeid {
 	a.addNodes(sig.Params(), "func.params")
 	a.addNodes(sig.Results(), "func.results")
 	a.endObject(obj, nil, fn).tags |= otFunction
-
-	if a.log != nil {
-		fmt.Fprintf(a.log, "\t----\n")
-	}
+	a.recordOrigin(obj+1, funcObjectOrigin{fn}, uint32(a.nextNode()-obj-1))
 
 	return obj
 }
@@ -373,7 +409,7 @@ func (a *analysis) offsetOf(typ types.Type, index int) uint32 {
 			offset += a.sizeof(t.Field(i).Type())
 		}
 	default:
-		panic(fmt.Sprintf("offsetOf(%s : %T)", typ, typ))
+		a.invalid("offsetOf(%s : %T)", typ, typ)
 	}
 	return offset
 }
@@ -390,7 +426,10 @@ func (a *analysis) flatten(t types.Type) []*subEleInfo {
 		switch t := t.(type) {
 		case *types.Named:
 			u := t.Underlying()
-			if isInterface(u) {
+			if a.reflectValue != nil && types.Identical(t, a.reflectValue) {
+				// A reflect.Value is pointer-like; see reflect.go.
+				fl = append(fl, &subEleInfo{typ: t})
+			} else if isInterface(u) {
 				// Debuggability hack: don't remove
 				// the named type from interfaces as
 				// they're very verbose.
@@ -440,7 +479,9 @@ func (a *analysis) flatten(t types.Type) []*subEleInfo {
 			}
 
 		default:
-			panic(fmt.Sprintf("cannot flatten unsupported type %T", t))
+			// Skipped: an opaque node.
+			a.unsupported(nil, nil, "cannot flatten unsupported type %T", t)
+			fl = append(fl, &subEleInfo{typ: t})
 		}
 
 		a.flattenBuf[t] = fl
@@ -0,0 +1,183 @@
+package pa
+
+import (
+	"fmt"
+	"go/token"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// With Options.Provenance, the analysis records for each fact of each
+// points-to set the first node it was copied or derived from, and for each
+// call edge resolved by a rule the fact that resolved it. The facts without
+// a recorded source are the initial ones, of the allocation sites, including
+// the objects allocated by intrinsics and summaries.
+
+type provKey struct {
+	node, obj nodeid
+}
+
+// Origins of the nodes that are not those of an ssa.Value.
+type (
+	funcObjectOrigin struct{ fn *ssa.Function }         // params and results of a function object
+	callBlockOrigin  struct{ site ssa.CallInstruction } // params/results block of a dynamic call
+)
+
+// An edgeCause is the fact obj in pts(node) that resolved a call edge.
+type edgeCause struct {
+	node, obj nodeid
+	cha       bool // resolved by CHA, as pts(node) is empty
+}
+
+// A WitnessStep is a step of a witness chain, explaining a points-to fact
+// or a call edge: a value, a parameter or a call, through which the object
+// flowed, or the call site.
+type WitnessStep struct {
+	Instr   ssa.Instruction // the instruction of the step, if any
+	Pos     token.Pos       // the position of the step, if known
+	Message string
+}
+
+func (s WitnessStep) String() string {
+	return s.Message
+}
+
+// recordFlow records src as the source of the facts of pts(src)
+// not yet in pts(dst), about to be copied to dst.
+func (a *analysis) recordFlow(dst, src nodeid, pts *nodeset) {
+	ndst := a.nodes[dst]
+	for _, x := range pts.AppendTo(a.provSpace[:0]) {
+		if !ndst.pts.set().Has(x) {
+			a.provenance[provKey{dst, nodeid(x)}] = src
+		}
+	}
+}
+
+// recordFact records src as the source of the fact obj, about to be added
+// to pts(dst) by a rule attached to src.
+func (a *analysis) recordFact(dst, obj, src nodeid) {
+	if a.opts.Provenance && !a.nodes[dst].pts.set().Has(int(obj)) {
+		a.provenance[provKey{dst, obj}] = src
+	}
+}
+
+// recordEdge records the fact that resolved the edge from caller to callee at site, if first.
+func (a *analysis) recordEdge(caller *funcnode, site ssa.CallInstruction, callee *funcnode, cause edgeCause) {
+	if a.opts.Provenance {
+		key := csEdgeKey{caller, site, callee}
+		if _, ok := a.edgeCauses[key]; !ok {
+			a.edgeCauses[key] = cause
+		}
+	}
+}
+
+// recordOrigin records v as the origin of the nodes of its value starting at id.
+func (a *analysis) recordOrigin(id nodeid, v interface{}, size uint32) {
+	if a.opts.Provenance && id != 0 {
+		for i := nodeid(0); i < nodeid(size); i++ {
+			a.origins[id+i] = v
+		}
+	}
+}
+
+// ExplainPointsTo returns a witness chain of how v may point to obj,
+// from the allocation of obj to v, in the first context v points to obj in.
+// It returns nil if v does not point to obj, or without Options.Provenance.
+func (r *Result) ExplainPointsTo(v ssa.Value, obj *Label) []WitnessStep {
+	if !r.a.opts.Provenance {
+		return nil
+	}
+	for _, id := range r.valueNodes(v) {
+		if r.a.nodes[id].pts.set().Has(int(obj.id)) {
+			return r.a.witness(id, obj.id)
+		}
+	}
+	return nil
+}
+
+// ExplainEdge returns a witness chain of how caller may call callee at
+// site: the witness of the callee value pointing to callee, or to the
+// receiver, for dynamic calls, followed by the call site.
+// site is nil for the calls from the root of the call graph and from intrinsics.
+// It returns nil if there is no such edge, or without Options.Provenance.
+func (r *Result) ExplainEdge(caller *ssa.Function, site ssa.CallInstruction, callee *ssa.Function) []WitnessStep {
+	a := r.a
+	if !a.opts.Provenance {
+		return nil
+	}
+	for _, key := range a.csedges {
+		if key.caller.fn != caller || key.site != site || key.callee.fn != callee {
+			continue
+		}
+
+		var steps []WitnessStep
+		cause, ok := a.edgeCauses[key]
+		if ok && !cause.cha {
+			steps = a.witness(cause.node, cause.obj)
+		}
+		switch {
+		case site != nil && cause.cha:
+			steps = append(steps, WitnessStep{site, site.Pos(), fmt.Sprintf("%s resolves by CHA to %s", site, callee)})
+		case site != nil:
+			steps = append(steps, WitnessStep{site, site.Pos(), fmt.Sprintf("%s calls %s", site, callee)})
+		case key.caller == a.root:
+			steps = append(steps, WitnessStep{nil, callee.Pos(), fmt.Sprintf("%s is an entry point", callee)})
+		default:
+			steps = append(steps, WitnessStep{nil, caller.Pos(), fmt.Sprintf("intrinsic %s calls %s", caller, callee)})
+		}
+		return steps
+	}
+	return nil
+}
+
+// witness returns the chain of the nodes obj flowed through to node,
+// after the allocation of obj.
+func (a *analysis) witness(node, obj nodeid) []WitnessStep {
+	var chain []WitnessStep
+	seen := make(map[nodeid]bool)
+	for id := node; !seen[id]; {
+		seen[id] = true
+		if step, ok := a.originStep(id); ok {
+			if n := len(chain); n == 0 || chain[n-1] != step {
+				chain = append(chain, step)
+			}
+		}
+		src, ok := a.provenance[provKey{id, obj}]
+		if !ok {
+			break
+		}
+		id = src
+	}
+
+	l := a.label(obj)
+	alloc := WitnessStep{Pos: l.Pos(), Message: fmt.Sprintf("allocation of %s", l)}
+	alloc.Instr, _ = l.Value().(ssa.Instruction)
+	steps := []WitnessStep{alloc}
+	for i := len(chain) - 1; i >= 0; i-- {
+		steps = append(steps, chain[i])
+	}
+	return steps
+}
+
+// originStep returns the step of the value, parameters or call the node id stands for.
+func (a *analysis) originStep(id nodeid) (WitnessStep, bool) {
+	switch o := a.origins[id].(type) {
+	case funcObjectOrigin:
+		return WitnessStep{nil, o.fn.Pos(), fmt.Sprintf("params or results of %s", o.fn)}, true
+	case callBlockOrigin:
+		return WitnessStep{o.site, o.site.Pos(), fmt.Sprintf("arguments or results of %s", o.site)}, true
+	case ssa.Value:
+		switch v := o.(type) {
+		case *ssa.Function:
+			return WitnessStep{nil, v.Pos(), fmt.Sprintf("function %s", v)}, true
+		case *ssa.Parameter:
+			return WitnessStep{nil, v.Pos(), fmt.Sprintf("parameter %s of %s", v.Name(), v.Parent())}, true
+		case *ssa.FreeVar:
+			return WitnessStep{nil, v.Pos(), fmt.Sprintf("free variable %s of %s", v.Name(), v.Parent())}, true
+		case ssa.Instruction:
+			return WitnessStep{v, v.Pos(), fmt.Sprintf("%s = %s in %s", o.Name(), v, v.Parent())}, true
+		}
+		return WitnessStep{nil, o.Pos(), o.String()}, true
+	}
+	return WitnessStep{}, false
+}
@@ -0,0 +1,86 @@
+package pa
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const provenanceSample = `package main
+
+type T struct{ f func() }
+
+func id(p *int) *int { return p }
+
+func hello() {}
+
+func use(p *int) {}
+
+func main() {
+	x := new(int)
+	use(id(x))
+	t := &T{f: hello}
+	t.f()
+}
+`
+
+func TestProvenance(t *testing.T) {
+	pkg := buildSample(t, provenanceSample)
+	p := sampleParam(t, pkg, "main.use", "p")
+	hello := sampleFunc(t, pkg, "main.hello")
+	main := sampleFunc(t, pkg, "main.main")
+	var site ssa.CallInstruction
+	for _, b := range main.Blocks {
+		for _, instr := range b.Instrs {
+			if c, ok := instr.(*ssa.Call); ok && c.Call.StaticCallee() == nil {
+				site = c
+			}
+		}
+	}
+	messages := func(steps []WitnessStep) []string {
+		var out []string
+		for _, step := range steps {
+			out = append(out, step.String())
+		}
+		return out
+	}
+	for _, prov := range []bool{false, true} {
+		res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{K: 1, Provenance: prov})
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj := res.PointsTo(p)[0]
+		var wantPts, wantEdge []string
+		if prov {
+			wantPts = []string{
+				"allocation of main.main:t0",
+				"t0 = new int (new) in main.main",
+				"parameter p of main.id",
+				"params or results of main.id",
+				"t1 = id(t0) in main.main",
+				"parameter p of main.use",
+			}
+			wantEdge = []string{
+				"allocation of main.hello",
+				"function main.hello",
+				"t6 = *t5 in main.main",
+				"t6() calls main.hello",
+			}
+		}
+		if got := messages(res.ExplainPointsTo(p, obj)); !reflect.DeepEqual(got, wantPts) {
+			t.Errorf("Provenance %v: ExplainPointsTo(p, %s) = %q, want %q", prov, obj, got, wantPts)
+		}
+		if got := messages(res.ExplainEdge(main, site, hello)); !reflect.DeepEqual(got, wantEdge) {
+			t.Errorf("Provenance %v: ExplainEdge(main, %s, hello) = %q, want %q", prov, site, got, wantEdge)
+		}
+
+		// No witness of what is not.
+		if got := res.ExplainEdge(main, site, sampleFunc(t, pkg, "main.use")); got != nil {
+			t.Errorf("Provenance %v: ExplainEdge of a missing edge = %q", prov, messages(got))
+		}
+		if fobj := res.PointsTo(sampleValue(t, pkg, "main.main", "t6"))[0]; res.ExplainPointsTo(p, fobj) != nil {
+			t.Errorf("Provenance %v: ExplainPointsTo(p, %s) explains a missing fact", prov, fobj)
+		}
+	}
+}
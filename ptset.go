@@ -0,0 +1,178 @@
+package pa
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// A ptset is the points-to set of a node, or its value at the previous
+// iteration, in the representation selected by Options.SharedPointsToSets.
+// The parallel solver changes distinct ptsets concurrently.
+type ptset interface {
+	set() *nodeset // the set, not to be changed; valid until the next change
+	add(id nodeid) bool
+	addAll(s *nodeset) bool
+	assign(s ptset) // sets to s, of the same representation
+	clear()
+}
+
+// newPtset returns an empty ptset.
+func (a *analysis) newPtset() ptset {
+	if a.ptsTable != nil {
+		return &sharedPtset{t: a.ptsTable}
+	}
+	return &plainPtset{}
+}
+
+// A plainPtset owns its set.
+type plainPtset struct {
+	nodeset
+}
+
+func (p *plainPtset) set() *nodeset  { return &p.nodeset }
+func (p *plainPtset) assign(s ptset) { p.Copy(&s.set().Sparse) }
+func (p *plainPtset) clear()         { p.Clear() }
+
+// A sharedPtset is hash-consed: once assigned to another ptset, as when
+// the solver saves pts as prev_pts, its set is interned in a ptsTable and
+// shared by all the equal sets, until it changes again (copy on write).
+type sharedPtset struct {
+	t     *ptsTable
+	own   *nodeset  // the set, if not shared
+	canon *ptsEntry // the set, if shared
+}
+
+func (p *sharedPtset) set() *nodeset {
+	if p.canon != nil {
+		return p.canon.set
+	}
+	if p.own == nil {
+		p.own = new(nodeset)
+	}
+	return p.own
+}
+
+// write returns the set to change, unshared.
+func (p *sharedPtset) write() *nodeset {
+	if p.canon != nil {
+		p.own = new(nodeset)
+		p.own.Copy(&p.canon.set.Sparse)
+		p.t.release(p.canon)
+		p.canon = nil
+	}
+	return p.set()
+}
+
+func (p *sharedPtset) add(id nodeid) bool {
+	if p.canon != nil && p.canon.set.Has(int(id)) {
+		return false
+	}
+	return p.write().add(id)
+}
+
+func (p *sharedPtset) addAll(s *nodeset) bool {
+	if p.canon != nil && s.SubsetOf(&p.canon.set.Sparse) {
+		return false
+	}
+	return p.write().addAll(s)
+}
+
+func (p *sharedPtset) assign(s ptset) {
+	q := s.(*sharedPtset)
+	if q.canon == nil {
+		if q.own == nil || q.own.IsEmpty() {
+			p.clear() // the empty set is not shared
+			return
+		}
+		q.canon = q.t.intern(q.own)
+		q.own = nil
+	}
+	if p.canon == q.canon {
+		return
+	}
+	p.clear()
+	p.canon = p.t.retain(q.canon)
+}
+
+func (p *sharedPtset) clear() {
+	if p.canon != nil {
+		p.t.release(p.canon)
+		p.canon = nil
+	}
+	p.own = nil
+}
+
+// A ptsTable holds the interned sets of the sharedPtsets, by hash.
+type ptsTable struct {
+	mu      sync.Mutex
+	entries map[uint64][]*ptsEntry
+	len     int // the number of entries
+}
+
+// A ptsEntry is an interned set, shared by refs sharedPtsets.
+type ptsEntry struct {
+	set  *nodeset
+	hash uint64
+	refs int
+}
+
+func newPtsTable() *ptsTable {
+	return &ptsTable{entries: make(map[uint64][]*ptsEntry)}
+}
+
+// intern returns the entry of the set equal to s, taking s if none,
+// with one more reference.
+func (t *ptsTable) intern(s *nodeset) *ptsEntry {
+	h := fnv.New64a()
+	var buf [8]byte
+	for _, x := range s.AppendTo(nil) {
+		for i := range buf {
+			buf[i] = byte(x >> (8 * i))
+		}
+		h.Write(buf[:])
+	}
+	hash := h.Sum64()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, e := range t.entries[hash] {
+		if e.set.Equals(&s.Sparse) {
+			e.refs++
+			return e
+		}
+	}
+	e := &ptsEntry{set: s, hash: hash, refs: 1}
+	t.entries[hash] = append(t.entries[hash], e)
+	t.len++
+	return e
+}
+
+// retain adds a reference to e.
+func (t *ptsTable) retain(e *ptsEntry) *ptsEntry {
+	t.mu.Lock()
+	e.refs++
+	t.mu.Unlock()
+	return e
+}
+
+// release removes a reference to e, and e itself from t after the last one.
+func (t *ptsTable) release(e *ptsEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e.refs--; e.refs > 0 {
+		return
+	}
+	bucket := t.entries[e.hash]
+	for i, f := range bucket {
+		if f == e {
+			bucket = append(bucket[:i], bucket[i+1:]...)
+			break
+		}
+	}
+	if len(bucket) == 0 {
+		delete(t.entries, e.hash)
+	} else {
+		t.entries[e.hash] = bucket
+	}
+	t.len--
+}
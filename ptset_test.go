@@ -0,0 +1,37 @@
+package pa
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestSharedPointsToSets(t *testing.T) {
+	for name, src := range parallelSamples {
+		pkg := buildSample(t, src)
+		for _, k := range []int{0, 1, 2} {
+			want := solution(t, pkg, &Options{K: k})
+			for _, opts := range []*Options{
+				{K: k, SharedPointsToSets: true},
+				{K: k, SharedPointsToSets: true, CycleElimination: true},
+			} {
+				if got := solution(t, pkg, opts); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s, %+v: got\n%s\nwant\n%s", name, *opts, strings.Join(got, "\n"), strings.Join(want, "\n"))
+				}
+			}
+		}
+	}
+
+	pkg := buildSample(t, parallelSamples["synthetic"])
+	for _, shared := range []bool{false, true} {
+		res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{K: 2, SharedPointsToSets: shared})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sets := res.Stats.SharedSets; shared != (sets > 0) || sets >= res.Stats.Nodes {
+			t.Errorf("SharedPointsToSets %v: %d sets stored for %d nodes", shared, sets, res.Stats.Nodes)
+		}
+	}
+}
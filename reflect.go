@@ -0,0 +1,392 @@
+package pa
+
+// Modeling of package reflect, after the reflection model of x/tools/go/pointer.
+//
+// A reflect.Value is a single pointer-like node, pointing to tagged
+// objects exactly as an interface does: the tag is the dynamic type of
+// the value, the payload is the value.
+//
+// A reflect.Type is an interface whose dynamic type is always *reflect.rtype.
+// The rtype of each type T is a canonical tagged object, whose data is T
+// and whose payload points to the tagged object itself, so that the methods
+// of *rtype invoked through reflect.Type receive it as their receiver.
+//
+// Objects created by the intrinsics are attributed to the clone of the
+// reflect function creating them.
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// makeRtype returns the canonical rtype object of T.
+func (a *analysis) makeRtype(T types.Type) nodeid {
+	if obj, ok := a.rtypes.At(T).(nodeid); ok {
+		return obj
+	}
+	obj := a.makeInterfaceObj(a.reflectRtypePtr, nil, T)
+	a.nodes[obj+1].pts.add(obj)
+	a.addWork(obj + 1)
+	a.rtypes.Set(T, obj)
+	return obj
+}
+
+// rtypeTaggedValue returns the type denoted by the rtype object obj,
+// or nil if obj is not an rtype object.
+func (a *analysis) rtypeTaggedValue(obj nodeid) types.Type {
+	tDyn, _, _ := a.taggedValue(obj)
+	if tDyn != a.reflectRtypePtr {
+		return nil
+	}
+	return a.nodes[obj].obj.data.(types.Type)
+}
+
+// makeBoundMethod returns a new function object for method fn bound to
+// the receiver value at recv, as made by reflect.Value.Method.
+// It is called by fpRule, which copies the receiver from recv.
+func (a *analysis) makeBoundMethod(fn *ssa.Function, recv nodeid) nodeid {
+	obj := a.addOneNode(fn.Signature, "reflect.bound", nil)
+	a.endObject(obj, nil, fn)
+	a.boundrecv[obj] = recv
+	return obj
+}
+
+// ---------- intrinsics ----------
+
+// func ValueOf(i any) Value
+// func (v Value) Interface() any
+func extReflectValueOf(a *analysis, fc *funcnode) {
+	a.addflow(a.funcResults(fc.obj), a.funcParams(fc.obj), 1, nil)
+}
+
+func extReflectValueInterface(a *analysis, fc *funcnode) {
+	a.addflow(a.funcResults(fc.obj), a.funcParams(fc.obj), 1, nil)
+}
+
+// func TypeOf(i any) Type
+func extReflectTypeOf(a *analysis, fc *funcnode) {
+	a.addRule(a.funcParams(fc.obj), &typeOfRule{a.funcResults(fc.obj), a.funcParams(fc.obj)})
+}
+
+// func (v Value) Type() Type
+func extReflectValueType(a *analysis, fc *funcnode) {
+	a.addRule(a.funcParams(fc.obj), &typeOfRule{a.funcResults(fc.obj), a.funcParams(fc.obj)})
+}
+
+// func New(typ Type) Value
+func extReflectNew(a *analysis, fc *funcnode) {
+	a.addRule(a.funcParams(fc.obj), &reflectNewRule{fc: fc, result: a.funcResults(fc.obj)})
+}
+
+// func (v Value) Elem() Value
+func extReflectValueElem(a *analysis, fc *funcnode) {
+	a.addRule(a.funcParams(fc.obj), &rVElemRule{fc: fc, result: a.funcResults(fc.obj)})
+}
+
+// func (v Value) Field(i int) Value
+func extReflectValueField(a *analysis, fc *funcnode) {
+	a.addRule(a.funcParams(fc.obj), &rVFieldRule{fc: fc, result: a.funcResults(fc.obj)})
+}
+
+// func (v Value) Method(i int) Value
+// func (v Value) MethodByName(name string) Value
+func extReflectValueMethod(a *analysis, fc *funcnode) {
+	a.addRule(a.funcParams(fc.obj), &rVMethodRule{fc: fc, result: a.funcResults(fc.obj)})
+}
+
+// func (v Value) Call(in []Value) []Value
+func extReflectValueCall(a *analysis, fc *funcnode) {
+	params := a.funcParams(fc.obj) // v, in
+	res := a.funcResults(fc.obj)
+
+	// The elements of in.
+	args := a.addOneNode(a.reflectValue, "rVCall.args", nil)
+	a.genLoad(args, params+1, 1, 1)
+
+	// The array of results.
+	ret := a.nextNode()
+	a.addNodes(types.NewArray(a.reflectValue, 1), "rVCall.ret")
+	a.endObject(ret, fc, nil)
+	if a.nodes[res].pts.add(ret) {
+		a.addWork(res)
+	}
+
+	a.addRule(params, &rVCallRule{fc: fc, args: args, ret: ret + 1})
+}
+
+// func (t *rtype) Elem() Type
+func extReflectRtypeElem(a *analysis, fc *funcnode) {
+	a.addRule(a.funcParams(fc.obj), &rtypeElemRule{a.funcResults(fc.obj), a.funcParams(fc.obj)})
+}
+
+// ---------- rules ----------
+
+// The rules allocating objects fire once per object they are fired on.
+
+// result = TypeOf(x), for an interface or a reflect.Value x.
+// Attached to x.
+type typeOfRule struct {
+	result nodeid
+	x      nodeid
+}
+
+// result = New(typ). Attached to typ.
+type reflectNewRule struct {
+	fc     *funcnode
+	result nodeid
+	seen   nodeset // the objects of typ already fired on
+}
+
+// result = v.Elem(). Attached to v.
+type rVElemRule struct {
+	fc     *funcnode
+	result nodeid
+	seen   nodeset // the objects of v already fired on
+}
+
+// result = v.Field(_). Attached to v.
+type rVFieldRule struct {
+	fc     *funcnode
+	result nodeid
+	seen   nodeset // the objects of v already fired on
+}
+
+// result = v.Method(_). Attached to v.
+type rVMethodRule struct {
+	fc     *funcnode
+	result nodeid
+	seen   nodeset // the objects of v already fired on
+}
+
+// ret[0] = v.Call(args...). Attached to v.
+type rVCallRule struct {
+	fc   *funcnode
+	args nodeid  // the elements of the argument slice
+	ret  nodeid  // the element of the result array
+	seen nodeset // the objects of v already fired on
+}
+
+// result = t.Elem(). Attached to t.
+type rtypeElemRule struct {
+	result nodeid
+	t      nodeid
+}
+
+func (c *typeOfRule) addflow(a *analysis, delta *nodeset) {
+	for _, x := range delta.AppendTo(a.deltaSpace) {
+		tDyn, _, _ := a.taggedValue(nodeid(x))
+		rtype := a.makeRtype(tDyn)
+		a.recordFact(c.result, rtype, c.x)
+		if a.nodes[c.result].pts.add(rtype) {
+			a.addWork(c.result)
+		}
+	}
+}
+
+func (c *reflectNewRule) addflow(a *analysis, delta *nodeset) {
+	for _, x := range delta.AppendTo(a.deltaSpace) {
+		if !c.seen.add(nodeid(x)) {
+			continue
+		}
+		T := a.rtypeTaggedValue(nodeid(x))
+		if T == nil {
+			continue
+		}
+
+		// Allocate a T and wrap a pointer to it.
+		obj := a.nextNode()
+		a.addNodes(T, "reflect.New")
+		a.endObject(obj, c.fc, nil)
+
+		ptr := a.makeInterfaceObj(types.NewPointer(T), c.fc, nil)
+		a.nodes[ptr+1].pts.add(obj)
+		a.addWork(ptr + 1)
+		a.recordFact(c.result, ptr, a.funcParams(c.fc.obj))
+		if a.nodes[c.result].pts.add(ptr) {
+			a.addWork(c.result)
+		}
+	}
+}
+
+func (c *rVElemRule) addflow(a *analysis, delta *nodeset) {
+	for _, x := range delta.AppendTo(a.deltaSpace) {
+		if !c.seen.add(nodeid(x)) {
+			continue
+		}
+		tDyn, v, _ := a.taggedValue(nodeid(x))
+		switch t := tDyn.Underlying().(type) {
+		case *types.Interface:
+			// The payload holds the tagged objects of the dynamic value.
+			if a.auxaddflow(c.result, v) {
+				a.addWork(c.result)
+			}
+
+		case *types.Pointer:
+			// Wrap the pointee, loaded from the payload.
+			T := t.Elem()
+			obj := a.makeInterfaceObj(T, c.fc, nil)
+			for i := uint32(0); i < a.sizeof(T); i++ {
+				a.addRule(v, &loadRule{i, obj + 1 + nodeid(i)})
+			}
+			a.recordFact(c.result, obj, a.funcParams(c.fc.obj))
+			if a.nodes[c.result].pts.add(obj) {
+				a.addWork(c.result)
+			}
+		}
+	}
+}
+
+func (c *rVFieldRule) addflow(a *analysis, delta *nodeset) {
+	for _, x := range delta.AppendTo(a.deltaSpace) {
+		if !c.seen.add(nodeid(x)) {
+			continue
+		}
+		tDyn, v, _ := a.taggedValue(nodeid(x))
+		st, ok := tDyn.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		// The field index is unknown: wrap each field.
+		for i, n := 0, st.NumFields(); i < n; i++ {
+			F := st.Field(i).Type()
+			obj := a.makeInterfaceObj(F, c.fc, nil)
+			a.auxaddflowN(obj+1, v+nodeid(a.offsetOf(tDyn, i)), a.sizeof(F))
+			a.recordFact(c.result, obj, a.funcParams(c.fc.obj))
+			if a.nodes[c.result].pts.add(obj) {
+				a.addWork(c.result)
+			}
+		}
+	}
+}
+
+func (c *rVMethodRule) addflow(a *analysis, delta *nodeset) {
+	for _, x := range delta.AppendTo(a.deltaSpace) {
+		if !c.seen.add(nodeid(x)) {
+			continue
+		}
+		tDyn, v, _ := a.taggedValue(nodeid(x))
+		if isInterface(tDyn) {
+			continue
+		}
+		// The method is unknown: wrap each exported method, bound to the value.
+		mset := a.prog.MethodSets.MethodSet(tDyn)
+		for i, n := 0, mset.Len(); i < n; i++ {
+			sel := mset.At(i)
+			if !sel.Obj().Exported() {
+				continue
+			}
+			fn := a.prog.MethodValue(sel)
+			if fn == nil {
+				continue
+			}
+			sig := fn.Signature
+			T := types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), sig.Variadic())
+			obj := a.makeInterfaceObj(T, c.fc, nil)
+			a.nodes[obj+1].pts.add(a.makeBoundMethod(fn, v))
+			a.addWork(obj + 1)
+			a.recordFact(c.result, obj, a.funcParams(c.fc.obj))
+			if a.nodes[c.result].pts.add(obj) {
+				a.addWork(c.result)
+			}
+		}
+	}
+}
+
+func (c *rVCallRule) addflow(a *analysis, delta *nodeset) {
+	for _, x := range delta.AppendTo(a.deltaSpace) {
+		if !c.seen.add(nodeid(x)) {
+			continue
+		}
+		tDyn, v, _ := a.taggedValue(nodeid(x))
+		sig, ok := tDyn.Underlying().(*types.Signature)
+		if !ok {
+			continue
+		}
+
+		// Allocate a contiguous relay params/results block for the call.
+		block := a.nextNode()
+		a.addNodes(sig.Params(), "rVCall.params")
+		a.addNodes(sig.Results(), "rVCall.results")
+
+		// Unwrap the arguments into the params; the position of each is unknown.
+		p := block
+		for i, n := 0, sig.Params().Len(); i < n; i++ {
+			T := sig.Params().At(i).Type()
+			if sig.Variadic() && i == n-1 {
+				// The variadic arguments are passed in a new slice.
+				elems := a.nextNode()
+				a.addNodes(sliceToArray(T), "rVCall.variadic")
+				a.endObject(elems, c.fc, nil)
+				if a.nodes[p].pts.add(elems) {
+					a.addWork(p)
+				}
+				a.unwrapValue(T.Underlying().(*types.Slice).Elem(), elems+1, c.args)
+			} else {
+				a.unwrapValue(T, p, c.args)
+			}
+			p += nodeid(a.sizeof(T))
+		}
+
+		// Wrap the results into the result array.
+		r := p
+		for i, n := 0, sig.Results().Len(); i < n; i++ {
+			T := sig.Results().At(i).Type()
+			if isInterface(T) {
+				if a.auxaddflow(c.ret, r) {
+					a.addWork(c.ret)
+				}
+			} else {
+				obj := a.makeInterfaceObj(T, c.fc, nil)
+				a.auxaddflowN(obj+1, r, a.sizeof(T))
+				a.recordFact(c.ret, obj, a.funcParams(c.fc.obj))
+				if a.nodes[c.ret].pts.add(obj) {
+					a.addWork(c.ret)
+				}
+			}
+			r += nodeid(a.sizeof(T))
+		}
+
+		// Call the functions the value may hold.
+		a.addRule(v, &fpRule{caller: c.fc, params: block, value: v})
+	}
+}
+
+// unwrapValue copies the values of type T held by the reflect.Value
+// at src into dst.
+func (a *analysis) unwrapValue(T types.Type, dst, src nodeid) {
+	if isInterface(T) {
+		a.addRule(src, &typeFilterRule{T, dst, src})
+	} else {
+		a.addRule(src, &untagRule{T, dst, false})
+	}
+}
+
+func (c *rtypeElemRule) addflow(a *analysis, delta *nodeset) {
+	for _, x := range delta.AppendTo(a.deltaSpace) {
+		T := a.rtypeTaggedValue(nodeid(x))
+		if T == nil {
+			continue
+		}
+		var elem types.Type
+		switch t := T.Underlying().(type) {
+		case *types.Array:
+			elem = t.Elem()
+		case *types.Chan:
+			elem = t.Elem()
+		case *types.Map:
+			elem = t.Elem()
+		case *types.Pointer:
+			elem = t.Elem()
+		case *types.Slice:
+			elem = t.Elem()
+		default:
+			continue // reflect panics
+		}
+		rtype := a.makeRtype(elem)
+		a.recordFact(c.result, rtype, c.t)
+		if a.nodes[c.result].pts.add(rtype) {
+			a.addWork(c.result)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package pa
+
+import (
+	"sort"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// Result holds the results of the analysis:
+// the call graph, and the solved points-to sets for queries.
+type Result struct {
+	CallGraph   *callgraph.Graph // discovered call graph
+	CSCallGraph *CSCallGraph     // discovered call graph, between funcnodes
+
+	// FuncMetrics holds the metrics of the reachable functions measured by
+	// the context-insensitive pre-analysis, if Options.Introspection is set.
+	FuncMetrics map[*ssa.Function]*FuncMetrics
+
+	// Bodyless lists the reachable functions without body and without summary,
+	// in discovery order: assembly, //go:linkname and cgo stubs.
+	// See Options.ConservativeBodyless.
+	Bodyless []*ssa.Function
+
+	// Diagnostics lists the places where the analysis gave up on soundness,
+	// in discovery order.
+	Diagnostics []Diagnostic
+
+	// Approximate holds the edges of CallGraph resolved by CHA only,
+	// see Options.CHAFallback.
+	Approximate map[*callgraph.Edge]bool
+
+	Stats *Stats // statistics of the analysis
+
+	// Incomplete reports that the analysis, or the pre-analysis of
+	// Options.Introspection, was stopped before its fixpoint, by cancellation
+	// or a budget of Options, for the reason IncompleteReason.
+	// The results are then partial: missing points-to facts and call edges,
+	// or, from the pre-analysis, clones.
+	Incomplete       bool
+	IncompleteReason string
+
+	a *analysis
+}
+
+// ContextPointsTo is the points-to set of a value within one context
+// of its enclosing function.
+type ContextPointsTo struct {
+	Context Context
+	Labels  []*Label
+}
+
+// PointsTo returns the abstract objects that v may point to,
+// merged over all contexts the enclosing function was analyzed in.
+// v must be a pointer-like value (pointer, slice, map, chan, func or interface);
+// the result is empty for unreachable code and non-pointer-like values.
+func (r *Result) PointsTo(v ssa.Value) []*Label {
+	var ids nodeset
+	for _, id := range r.valueNodes(v) {
+		ids.addAll(r.a.nodes[id].pts.set())
+	}
+	return r.labels(&ids)
+}
+
+// PointsToByContext returns the points-to set of v separately
+// for each context its enclosing function was analyzed in,
+// ordered by the discovery of the contexts.
+// Values that are not context-sensitive (globals, functions, constants
+// and free variables) yield a single entry for the empty context.
+func (r *Result) PointsToByContext(v ssa.Value) []ContextPointsTo {
+	var res []ContextPointsTo
+	for _, fc := range r.a.contextsOf(v) {
+		res = append(res, ContextPointsTo{
+			Context: contextOf(fc),
+			Labels:  r.labels(r.a.nodes[r.a.valueNodeIn(fc, v)].pts.set()),
+		})
+	}
+	return res
+}
+
+// MayAlias reports whether x and y may point to the same object
+// in some contexts of their enclosing functions.
+// Both must be pointer-like values; see PointsTo.
+func (r *Result) MayAlias(x, y ssa.Value) bool {
+	var xpts, ypts nodeset
+	for _, id := range r.valueNodes(x) {
+		xpts.addAll(r.a.nodes[id].pts.set())
+	}
+	for _, id := range r.valueNodes(y) {
+		ypts.addAll(r.a.nodes[id].pts.set())
+	}
+	return xpts.Intersects(&ypts.Sparse)
+}
+
+// MayAliasInContext reports whether x, evaluated in context cx of its
+// enclosing function, and y, evaluated in context cy of its enclosing function,
+// may point to the same object.
+// Contexts are as reported by PointsToByContext; for two values of the same
+// function, passing the same context for both gives a context-sensitive answer.
+// Values that are not context-sensitive are found in the empty context, NewContext().
+// It returns false if either value was not analyzed in the given context.
+func (r *Result) MayAliasInContext(x ssa.Value, cx Context, y ssa.Value, cy Context) bool {
+	xid, ok := r.valueNodeByContext(x, cx)
+	if !ok {
+		return false
+	}
+	yid, ok := r.valueNodeByContext(y, cy)
+	if !ok {
+		return false
+	}
+	return r.a.nodes[xid].pts.set().Intersects(&r.a.nodes[yid].pts.set().Sparse)
+}
+
+// valueNodeByContext returns the node of v in context ctx, if any.
+func (r *Result) valueNodeByContext(v ssa.Value, ctx Context) (nodeid, bool) {
+	for _, fc := range r.a.contextsOf(v) {
+		if contextOf(fc) == ctx {
+			return r.a.valueNodeIn(fc, v), true
+		}
+	}
+	return 0, false
+}
+
+// valueNodes returns the node of v in each context it was analyzed in.
+func (r *Result) valueNodes(v ssa.Value) []nodeid {
+	var ids []nodeid
+	for _, fc := range r.a.contextsOf(v) {
+		ids = append(ids, r.a.valueNodeIn(fc, v))
+	}
+	return ids
+}
+
+// labels returns the Labels of the objects in pts, ordered by node.
+func (r *Result) labels(pts *nodeset) []*Label {
+	var labels []*Label
+	for _, x := range pts.AppendTo(nil) {
+		labels = append(labels, r.a.label(nodeid(x)))
+	}
+	return labels
+}
+
+// contextsOf returns the funcnodes in which v has a value node,
+// ordered by creation. A single nil funcnode denotes a global value.
+func (a *analysis) contextsOf(v ssa.Value) []*funcnode {
+	switch v.(type) {
+	case *ssa.Global, *ssa.Function, *ssa.Const, *ssa.FreeVar:
+		if _, ok := a.globalval[v]; ok {
+			return []*funcnode{nil}
+		}
+		return nil
+	}
+
+	fn := v.Parent()
+	if fn == nil {
+		return nil
+	}
+	var fcs []*funcnode
+	for _, obj := range a.csfuncobj[fn] {
+		if fc := a.nodes[obj].obj.funcn; fc != nil && fc.localval != nil {
+			if _, ok := fc.localval[v]; ok {
+				fcs = append(fcs, fc)
+			}
+		}
+	}
+	sort.Slice(fcs, func(i, j int) bool { return fcs[i].obj < fcs[j].obj })
+	return fcs
+}
+
+// valueNodeIn returns the node of v in funcnode fc, as found by contextsOf.
+func (a *analysis) valueNodeIn(fc *funcnode, v ssa.Value) nodeid {
+	if fc == nil {
+		return a.globalval[v]
+	}
+	return fc.localval[v]
+}
+
+// contextOf returns the context of fc, or the empty context for nil.
+func contextOf(fc *funcnode) Context {
+	if fc == nil {
+		return NewContext()
+	}
+	return fc.func_context
+}
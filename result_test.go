@@ -0,0 +1,141 @@
+package pa
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const resultSample = `package main
+
+type T struct{ f *int }
+
+var g *int
+
+func id(p *int) *int { return p }
+
+func dead(p *int) {}
+
+func main() {
+	x := new(int)
+	y := new(int)
+	a := id(x)
+	b := id(y)
+	t := &T{f: x}
+	g = t.f
+	println(a, b, g)
+}
+`
+
+// sampleValue returns the value of the function fn of pkg named name,
+// e.g. "t0", a parameter or an instruction.
+func sampleValue(t testing.TB, pkg *ssa.Package, fn, name string) ssa.Value {
+	f := sampleFunc(t, pkg, fn)
+	for _, p := range f.Params {
+		if p.Name() == name {
+			return p
+		}
+	}
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			if v, ok := instr.(ssa.Value); ok && v.Name() == name {
+				return v
+			}
+		}
+	}
+	t.Fatalf("no value %s of %s", name, fn)
+	return nil
+}
+
+func TestPointsTo(t *testing.T) {
+	pkg := buildSample(t, resultSample)
+	for _, k := range []int{0, 1} {
+		res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{K: k})
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, y := sampleValue(t, pkg, "main.main", "t0"), sampleValue(t, pkg, "main.main", "t1")
+		a, b := sampleValue(t, pkg, "main.main", "t2"), sampleValue(t, pkg, "main.main", "t3")
+		g := pkg.Var("g")
+		for _, test := range []struct {
+			v    ssa.Value
+			want []string
+		}{
+			{x, []string{"main.main:t0"}},
+			{y, []string{"main.main:t1"}},
+			{a, map[int][]string{0: {"main.main:t0", "main.main:t1"}, 1: {"main.main:t0"}}[k]},
+			{sampleParam(t, pkg, "main.id", "p"), []string{"main.main:t0", "main.main:t1"}},
+			{g, []string{"main.g"}},
+		} {
+			if got := labels(res, test.v); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("K %d: %s points to %v, want %v", k, test.v.Name(), got, test.want)
+			}
+		}
+		for _, test := range []struct {
+			x, y ssa.Value
+			want bool
+		}{
+			{x, y, false},
+			{x, a, true},
+			{y, a, k == 0},
+			{a, b, k == 0},
+		} {
+			if got := res.MayAlias(test.x, test.y); got != test.want {
+				t.Errorf("K %d: MayAlias(%s, %s) = %v, want %v", k, test.x.Name(), test.y.Name(), got, test.want)
+			}
+		}
+		if got := labels(res, sampleParam(t, pkg, "main.dead", "p")); len(got) != 0 {
+			t.Errorf("K %d: the param of an unreachable function points to %v", k, got)
+		}
+	}
+}
+
+func TestPointsToByContext(t *testing.T) {
+	pkg := buildSample(t, resultSample)
+	res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{K: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := sampleParam(t, pkg, "main.id", "p")
+	byCtx := res.PointsToByContext(p)
+	var got [][]string
+	for _, c := range byCtx {
+		var ls []string
+		for _, l := range c.Labels {
+			ls = append(ls, l.String())
+		}
+		got = append(got, ls)
+	}
+	if want := [][]string{{"main.main:t0"}, {"main.main:t1"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("p points to %v by context, want %v", got, want)
+	}
+	c0, c1 := byCtx[0].Context, byCtx[1].Context
+	if c0.Len() != 1 || c1.Len() != 1 || c0 == c1 {
+		t.Errorf("contexts %s and %s, want two distinct call sites", c0, c1)
+	}
+
+	x := sampleValue(t, pkg, "main.main", "t0")
+	for _, test := range []struct {
+		x    ssa.Value
+		cx   Context
+		y    ssa.Value
+		cy   Context
+		want bool
+	}{
+		{p, c0, p, c0, true},
+		{p, c0, p, c1, false},
+		{p, c0, x, NewContext(), true},
+		{p, c1, x, NewContext(), false},
+		{p, NewContext(), x, NewContext(), false}, // id is not analyzed in the empty context
+	} {
+		if got := res.MayAliasInContext(test.x, test.cx, test.y, test.cy); got != test.want {
+			t.Errorf("MayAliasInContext(%s, %s, %s, %s) = %v, want %v", test.x.Name(), test.cx, test.y.Name(), test.cy, got, test.want)
+		}
+	}
+
+	// Globals are found in the empty context.
+	if got := res.PointsToByContext(pkg.Var("g")); len(got) != 1 || got[0].Context.Len() != 0 {
+		t.Errorf("g points to %v by context, want a single entry in the empty context", got)
+	}
+}
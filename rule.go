@@ -1,7 +1,6 @@
 package pa
 
 import (
-	"fmt"
 	"go/types"
 
 	"golang.org/x/tools/go/ssa"
@@ -27,12 +26,14 @@ type storeRule struct {
 type offsetAddrRule struct {
 	offset uint32
 	d      nodeid
+	s      nodeid
 }
 
 // d = s.(typ)  where typ is an interface
 type typeFilterRule struct {
 	typ types.Type // an interface type
 	d   nodeid
+	s   nodeid
 }
 
 type untagRule struct {
@@ -48,13 +49,35 @@ type invokeRule struct {
 	site   ssa.CallInstruction
 	method *types.Func // the abstract method
 	params nodeid      // the start of the identity/params/results block
+	recvs  nodeset     // payloads a receiverRule is attached to
+	value  nodeid      // the interface value, the rule is attached to
+}
+
+// recv.method(params...) with a statically known method,
+// resolved per receiver object for receiver-sensitive contexts.
+// Attached to recv.
+type receiverRule struct {
+	caller *funcnode
+	site   ssa.CallInstruction
+	fn     *ssa.Function
+	params nodeid // the start of the params/results block, sans receiver
+	recv   nodeid // the receiver, the rule is attached to
 }
 
 // fp
 type fpRule struct {
 	caller *funcnode
-	site   ssa.CallInstruction
-	params nodeid // the start of the identity/params/results block
+	site   ssa.CallInstruction // nil for calls made by intrinsics
+	params nodeid              // the start of the identity/params/results block
+	value  nodeid              // the func value, the rule is attached to
+}
+
+// attachRule attaches r to node id.
+func (a *analysis) attachRule(id nodeid, r rule) {
+	a.nodes[id].fly_solve = append(a.nodes[id].fly_solve, r)
+	if a.tracer != nil {
+		a.tracer.RuleAdded(int(id), ruleKind(r))
+	}
 }
 
 // The size of the copy is implicitly 1.
@@ -62,10 +85,11 @@ type fpRule struct {
 func (a *analysis) auxaddflow(dst, src nodeid) bool {
 	if dst != src {
 		if nsrc := a.nodes[src]; nsrc.flow_to.add(dst) {
-			if a.log != nil {
-				fmt.Fprintf(a.log, "\t\t\tdynamic copy n%d <- n%d\n", dst, src)
+			a.flowVersion++
+			if a.opts.Provenance {
+				a.recordFlow(dst, src, nsrc.pts.set())
 			}
-			return a.nodes[dst].pts.addAll(&nsrc.pts)
+			return a.nodes[dst].pts.addAll(nsrc.pts.set())
 		}
 	}
 	return false
@@ -112,6 +136,7 @@ func (c *offsetAddrRule) addflow(a *analysis, delta *nodeset) {
 	dst := a.nodes[c.d]
 	for _, x := range delta.AppendTo(a.deltaSpace) {
 		k := nodeid(x)
+		a.recordFact(c.d, k+nodeid(c.offset), c.s)
 		if dst.pts.add(k + nodeid(c.offset)) {
 			a.addWork(c.d)
 		}
@@ -125,10 +150,12 @@ func (c *typeFilterRule) addflow(a *analysis, delta *nodeset) {
 		if indirect {
 			// TODO(adonovan): we'll need to implement this
 			// when we start creating indirect tagged objects.
-			panic("indirect tagged object")
+			a.unsupported(nil, nil, "indirect tagged object n%d", ifaceObj)
+			continue
 		}
 
 		if types.AssignableTo(tDyn, c.typ) {
+			a.recordFact(c.d, ifaceObj, c.s)
 			if a.nodes[c.d].pts.add(ifaceObj) {
 				a.addWork(c.d)
 			}
@@ -165,58 +192,39 @@ func (c *invokeRule) addflow(a *analysis, delta *nodeset) {
 		// Look up the concrete method.
 		fn := a.prog.LookupMethod(tDyn, c.method.Pkg(), c.method.Name())
 		if fn == nil {
-			panic(fmt.Sprintf("no ssa.Function for %s", c.method))
+			a.unsupported(c.caller.fn, c.site, "no ssa.Function for %s of %s", c.method, tDyn)
+			continue
 		}
 
-		if pkg := fn.Pkg; pkg != nil {
-			if pkg.Pkg.Name() == "reflect" {
-				continue
-			}
-			if pkg.Pkg.Name() == "runtime" {
+		sig := fn.Signature
+
+		// Resolve pointer-receiver methods per pointee of the payload, if required.
+		if a.selector.ReceiverSensitive() {
+			if _, ok := tDyn.Underlying().(*types.Pointer); ok {
+				if c.recvs.add(v) {
+					r := &receiverRule{c.caller, c.site, fn, c.params, v}
+					a.attachRule(v, r)
+					a.recvsites = append(a.recvsites, r)
+					for _, y := range a.nodes[v].pts.set().AppendTo(nil) {
+						r.resolve(a, nodeid(y))
+					}
+				}
 				continue
 			}
 		}
 
-		sig := fn.Signature
-
-		var obj nodeid
-
 		// Find related context, if exists.
 		// or create a new function object with context generated
-		new_context := c.caller.func_context.GenContext(c.site)
-		if _, ok := a.csfuncobj[fn]; !ok {
-			a.csfuncobj[fn] = make(map[context]nodeid, 0)
-		}
-		newly_add := false
-		if selectiveContextPolicy(fn) {
-			if func_obj, ok2 := a.csfuncobj[fn][new_context]; ok2 {
-				obj = func_obj
-			} else {
-				obj = a.makeFunctionObject(fn)
-				a.csfuncobj[fn][new_context] = obj
-				newly_add = true
-			}
-		} else {
-			if func_obj, ok2 := a.csfuncobj[fn][NewContext()]; ok2 {
-				obj = func_obj
-			} else {
-				obj = a.makeFunctionObject(fn)
-				a.csfuncobj[fn][NewContext()] = obj
-				newly_add = true
-			}
-		}
-
-		if newly_add {
-			new_funcnode := &funcnode{fn, obj, new_context}
-
-			// Set called function obj's obj field.
-			a.nodes[obj].obj.funcn = new_funcnode
+		obj := a.funcObject(&Invocation{
+			Caller:   c.caller.fn,
+			Context:  c.caller.func_context,
+			Site:     c.site,
+			Callee:   fn,
+			Receiver: a.label(ifaceObj),
+		})
 
-			// Add newly added funcnode into reachable queue
-			a.addReachable(*new_funcnode)
-		}
-
-		a.addCallGraphEdge(c.caller.fn, c.site, fn)
+		a.addCallGraphEdge(c.caller, c.site, a.nodes[obj].obj.funcn)
+		a.recordEdge(c.caller, c.site, a.nodes[obj].obj.funcn, edgeCause{node: c.value, obj: ifaceObj})
 
 		// Extract value and connect to method's receiver.
 		// Copy payload to method's receiver param (arg0).
@@ -239,6 +247,74 @@ func (c *invokeRule) addflow(a *analysis, delta *nodeset) {
 	}
 }
 
+func (c *receiverRule) addflow(a *analysis, delta *nodeset) {
+	for _, x := range delta.AppendTo(a.deltaSpace) {
+		c.resolve(a, nodeid(x))
+	}
+}
+
+// resolve connects the call to the clone of c.fn selected for receiver object recvObj,
+// or, if recvObj is 0, to that selected without receiver object.
+func (c *receiverRule) resolve(a *analysis, recvObj nodeid) {
+	inv := &Invocation{
+		Caller:  c.caller.fn,
+		Context: c.caller.func_context,
+		Site:    c.site,
+		Callee:  c.fn,
+	}
+	if recvObj != 0 {
+		inv.Receiver = a.label(recvObj)
+	}
+	obj := a.funcObject(inv)
+
+	a.addCallGraphEdge(c.caller, c.site, a.nodes[obj].obj.funcn)
+
+	// The receiver param of this clone points to recvObj only,
+	// or to the receiver of the call.
+	arg0 := a.funcParams(obj)
+	if recvObj == 0 {
+		if a.auxaddflow(arg0, c.recv) {
+			a.addWork(arg0)
+		}
+	} else {
+		a.recordEdge(c.caller, c.site, a.nodes[obj].obj.funcn, edgeCause{node: c.recv, obj: recvObj})
+		a.recordFact(arg0, recvObj, c.recv)
+		if a.nodes[arg0].pts.add(recvObj) {
+			a.addWork(arg0)
+		}
+	}
+
+	sig := c.fn.Signature
+	src := c.params
+	dst := arg0 + 1
+
+	// Copy caller's argument block to method formal parameters.
+	paramsSize := a.sizeof(sig.Params())
+	a.auxaddflowN(dst, src, paramsSize)
+	src += nodeid(paramsSize)
+	dst += nodeid(paramsSize)
+
+	// Copy method results to caller's result block.
+	resultsSize := a.sizeof(sig.Results())
+	a.auxaddflowN(src, dst, resultsSize)
+}
+
+// resolveEmptyReceivers connects the pending receiver-resolved calls whose
+// receiver still points to nothing, as a nil *T, to the clone selected
+// without receiver object, and reports whether any was.
+func (a *analysis) resolveEmptyReceivers() bool {
+	resolved := false
+	for len(a.recvsites) > 0 {
+		c := a.recvsites[0]
+		a.recvsites = a.recvsites[1:]
+		if a.nodes[c.recv].pts.set().IsEmpty() {
+			c.resolve(a, 0)
+			resolved = true
+		}
+	}
+	return resolved
+}
+
 func (c *fpRule) addflow(a *analysis, delta *nodeset) {
 
 	for _, x := range delta.AppendTo(a.deltaSpace) {
@@ -246,63 +322,39 @@ func (c *fpRule) addflow(a *analysis, delta *nodeset) {
 
 		// Look up the concrete method.
 		var fn *ssa.Function
-		var ok bool
-		if fn, ok = a.nodes[funcobj].obj.data.(*ssa.Function); !ok {
-			panic(fmt.Sprintf("no ssa.Function for %s", c.site))
+		if obj := a.nodes[funcobj].obj; obj != nil {
+			fn, _ = obj.data.(*ssa.Function)
+		}
+		if fn == nil {
+			a.unsupported(c.caller.fn, c.site, "no ssa.Function for n%d", funcobj)
+			continue
 		}
 
 		sig := fn.Signature
 
-		var obj nodeid
-
 		// Find related context, if exists.
 		// or create a new function object with context generated
-		new_context := c.caller.func_context.GenContext(c.site)
-		if _, ok := a.csfuncobj[fn]; !ok {
-			a.csfuncobj[fn] = make(map[context]nodeid, 0)
-		}
-		newly_add := false
-		if selectiveContextPolicy(fn) {
-			if func_obj, ok2 := a.csfuncobj[fn][new_context]; ok2 {
-				obj = func_obj
-			} else {
-				obj = a.makeFunctionObject(fn)
-				a.csfuncobj[fn][new_context] = obj
-				newly_add = true
-			}
-		} else {
-			if func_obj, ok2 := a.csfuncobj[fn][NewContext()]; ok2 {
-				obj = func_obj
-			} else {
-				obj = a.makeFunctionObject(fn)
-				a.csfuncobj[fn][NewContext()] = obj
-				newly_add = true
-			}
-		}
-
-		if newly_add {
-			new_funcnode := &funcnode{fn, obj, new_context}
-
-			// Set called function obj's obj field.
-			a.nodes[obj].obj.funcn = new_funcnode
-
-			// Add newly added funcnode into reachable queue
-			a.addReachable(*new_funcnode)
-		}
+		obj := a.funcObject(&Invocation{Caller: c.caller.fn, Context: c.caller.func_context, Site: c.site, Callee: fn})
 		//fmt.Println(newly_add, fn.Signature, fn.Name(), fn.FreeVars)
 
-		a.addCallGraphEdge(c.caller.fn, c.site, fn)
+		a.addCallGraphEdge(c.caller, c.site, a.nodes[obj].obj.funcn)
+		a.recordEdge(c.caller, c.site, a.nodes[obj].obj.funcn, edgeCause{node: c.value, obj: funcobj})
 
 		/*
 			// flush freevars
 			for _, fre := range fn.FreeVars {
-				a.nodes[a.valueNode(fre)].prev_pts.Clear()
+				a.nodes[a.valueNode(fre)].prev_pts.clear()
 				a.worklist.add(a.valueNode(fre))
 			}*/
 
 		src := c.params
 		dst := a.funcParams(obj)
 
+		// Copy the receiver of a bound method, made by reflect.Value.Method.
+		if recv := sig.Recv(); recv != nil {
+			dst += nodeid(a.auxaddflowN(dst, a.boundrecv[funcobj], a.sizeof(recv.Type())))
+		}
+
 		// Copy caller's argument block to method formal parameters.
 		paramsSize := a.sizeof(sig.Params())
 		a.auxaddflowN(dst, src, paramsSize)
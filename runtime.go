@@ -0,0 +1,77 @@
+package pa
+
+// Modeling of package runtime.
+//
+// The runtime is full of unsafe code and has few interesting effects on
+// aliasing, so its functions are not analyzed: those not modeled here have
+// no effect. The models cover the functions returning fresh runtime
+// objects and those calling back into the program.
+
+import (
+	"go/types"
+)
+
+// func SetFinalizer(obj any, finalizer any)
+func extRuntimeSetFinalizer(a *analysis, fc *funcnode) {
+	params := a.funcParams(fc.obj) // obj, finalizer
+	a.addRule(params+1, &setFinalizerRule{fc: fc, obj: params})
+}
+
+// func AddCleanup[T, S any](ptr *T, cleanup func(S), arg S) Cleanup
+func extRuntimeAddCleanup(a *analysis, fc *funcnode) {
+	params := a.funcParams(fc.obj) // ptr, cleanup, arg
+	sig := fc.fn.Signature
+	cleanup := params + nodeid(a.sizeof(sig.Params().At(0).Type()))
+	arg := cleanup + nodeid(a.sizeof(sig.Params().At(1).Type()))
+
+	// arg is the params block of the call; cleanup has no results.
+	a.addRule(cleanup, &fpRule{caller: fc, params: arg, value: cleanup})
+}
+
+// func FuncForPC(pc uintptr) *Func
+// func CallersFrames(callers []uintptr) *Frames
+func extRuntimeAlloc(a *analysis, fc *funcnode) {
+	res := a.funcResults(fc.obj)
+	obj := a.nextNode()
+	a.addNodes(mustDeref(fc.fn.Signature.Results().At(0).Type()), fc.fn.Name())
+	a.endObject(obj, fc, nil)
+	if a.nodes[res].pts.add(obj) {
+		a.addWork(res)
+	}
+}
+
+// finalizer(obj), called by the runtime.
+// Attached to finalizer.
+type setFinalizerRule struct {
+	fc   *funcnode
+	obj  nodeid
+	seen nodeset // the objects of finalizer already fired on
+}
+
+func (c *setFinalizerRule) addflow(a *analysis, delta *nodeset) {
+	for _, x := range delta.AppendTo(a.deltaSpace) {
+		if !c.seen.add(nodeid(x)) {
+			continue
+		}
+		tDyn, v, _ := a.taggedValue(nodeid(x))
+		sig, ok := tDyn.Underlying().(*types.Signature)
+		if !ok || sig.Params().Len() != 1 {
+			continue // the runtime panics
+		}
+
+		// Allocate a contiguous relay params/results block for the call,
+		// whose only param receives obj.
+		block := a.nextNode()
+		a.addNodes(sig.Params(), "SetFinalizer.params")
+		a.addNodes(sig.Results(), "SetFinalizer.results")
+
+		T := sig.Params().At(0).Type()
+		if isInterface(T) {
+			a.addRule(c.obj, &typeFilterRule{T, block, c.obj})
+		} else {
+			a.addRule(c.obj, &untagRule{T, block, false})
+		}
+
+		a.addRule(v, &fpRule{caller: c.fc, params: block, value: v})
+	}
+}
@@ -3,6 +3,7 @@ package pa
 import (
 	"fmt"
 	"go/types"
+	"time"
 
 	"golang.org/x/tools/go/callgraph"
 	"golang.org/x/tools/go/ssa"
@@ -10,19 +11,31 @@ import (
 
 // duplication check is done before.
 // that is to say, a fc passed here should not be analyzed before.
-func (a *analysis) addReachable(fc funcnode) {
+// Called during constraint generation, fc is queued behind the current function.
+func (a *analysis) addReachable(fc *funcnode) {
+	if a.tracer != nil {
+		a.tracer.FuncReachable(fc.fn, fc.func_context)
+	}
+
 	// queue for deterministic func call
-	a.reachable_queue = make([]*funcnode, 0)
-	a.reachable_queue = append(a.reachable_queue, &fc)
+	a.reachable_queue = append(a.reachable_queue, fc)
+	if a.generating {
+		return
+	}
 
-	for len(a.reachable_queue) > 0 {
+	a.generating = true
+	start := time.Now()
+	for len(a.reachable_queue) > 0 && !a.budgetExceeded(true) {
 		cfc := a.reachable_queue[0]
 		a.reachable_queue = a.reachable_queue[1:]
 		a.genFunc(cfc)
 	}
+	a.curfn, a.curinstr = nil, nil
+	a.stats.GenTime += time.Since(start)
+	a.generating = false
 
 	for _, x := range a.globalflushbuf.AppendTo(a.flushSpace) {
-		a.nodes[nodeid(x)].prev_pts.Clear()
+		a.nodes[nodeid(x)].prev_pts.clear()
 		a.worklist.add(nodeid(x))
 	}
 	a.globalflushbuf.Clear()
@@ -31,16 +44,24 @@ func (a *analysis) addReachable(fc funcnode) {
 }
 
 // one level spread
-func (a *analysis) propagate(n *node, delta *nodeset) {
-	n.prev_pts.Copy(&n.pts.Sparse)
+func (a *analysis) propagate(id nodeid, n *node, delta *nodeset) {
+	n.prev_pts.assign(n.pts)
 
 	var copySeen nodeset
 	for _, x := range n.flow_to.AppendTo(a.deltaSpace) {
 		mid := nodeid(x)
 		if copySeen.add(mid) {
-			if a.nodes[mid].pts.addAll(delta) {
+			a.stats.Propagations++
+			if a.opts.Provenance {
+				a.recordFlow(mid, id, delta)
+			}
+			grew := a.nodes[mid].pts.addAll(delta)
+			if grew {
 				a.addWork(mid)
 			}
+			if a.cycleElimination() && !grew {
+				a.checkCycle(n, mid)
+			}
 		} else {
 			fmt.Println("????? duplication???s")
 		}
@@ -48,6 +69,7 @@ func (a *analysis) propagate(n *node, delta *nodeset) {
 }
 
 func (a *analysis) solve() {
+	start := time.Now()
 
 	// ------------ init -----------------
 
@@ -62,94 +84,87 @@ func (a *analysis) solve() {
 	a.CallGraph = callgraph.New(root_func)
 	a.callgraph = make(map[*ssa.Function]map[ssa.CallInstruction]map[*ssa.Function]bool)
 	a.callgraph[root_func] = make(map[ssa.CallInstruction]map[*ssa.Function]bool)
+	a.root = &funcnode{fn: root_func}
 
 	for _, entry := range a.entryfuns {
 
-		if a.log != nil {
-			fmt.Fprintf(a.log, "\troot call to %s:\n", entry)
-		}
-		a.addCallGraphEdge(root_func, nil, entry)
-		if a.log != nil {
-			fmt.Fprintf(a.log, "\tCallGraph: %s --> %s:\n", root_func.Name(), entry.Name())
-		}
 		new_func_obj_id := a.makeFunctionObject(entry)
 		new_context := NewContext()
 		if _, ok := a.csfuncobj[entry]; !ok {
-			a.csfuncobj[entry] = make(map[context]nodeid)
+			a.csfuncobj[entry] = make(map[Context]nodeid)
 		}
 		a.csfuncobj[entry][new_context] = new_func_obj_id
-		a.addReachable(funcnode{entry, new_func_obj_id, new_context})
+		new_funcnode := &funcnode{fn: entry, obj: new_func_obj_id, func_context: new_context}
+		a.nodes[new_func_obj_id].obj.funcn = new_funcnode
+
+		a.addCallGraphEdge(a.root, nil, new_funcnode)
+		a.addReachable(new_funcnode)
 
 	}
 
 	// ------------ worklist iteration -----------------
 
-	if a.log != nil {
-		fmt.Fprintf(a.log, "\n\n----- Solving through worklist ---------\n\n")
+	if a.parallel() {
+		a.solveWaves()
+	} else {
+		a.solveWorklist()
+	}
+
+	if !a.nodes[0].pts.set().IsEmpty() {
+		a.unsupported(nil, nil, "internal error: pts(n0) is nonempty: %s", a.nodes[0].pts.set())
 	}
 
+	a.collectStats()
+	a.stats.SolveTime = time.Since(start) - a.stats.GenTime
+
+	// Release buffer except for final pts
+	for _, n := range a.nodes {
+		n.fly_solve = nil
+		n.flow_to.Clear()
+		n.prev_pts.clear()
+	}
+
+}
+
+// solveWorklist runs the worklist iteration, one node at a time.
+func (a *analysis) solveWorklist() {
 	var delta nodeset
 	for {
-		var x int
-		if !a.worklist.TakeMin(&x) {
-			break // empty
+		a.iterations++
+		if a.budgetExceeded(a.iterations%ctxPollInterval == 0) {
+			break
 		}
-		id := nodeid(x)
-		if a.log != nil {
-			fmt.Fprintf(a.log, "\ttake node n%d\n", id)
+		a.fireQueued()
+		id, ok := a.worklist.take()
+		if !ok {
+			if a.flushConservative() || a.resolveEmptyReceivers() || a.resolveByCHA() {
+				continue
+			}
+			break // empty
 		}
-
+		a.stats.WorklistPops++
 		n := a.nodes[id]
 
 		// Difference propagation.
-		delta.Difference(&n.pts.Sparse, &n.prev_pts.Sparse)
+		delta.Difference(&n.pts.set().Sparse, &n.prev_pts.set().Sparse)
 		if delta.IsEmpty() {
 			continue
 		}
-		a.propagate(n, &delta)
+		a.propagate(id, n, &delta)
 
-		if a.log != nil {
-			fmt.Fprintf(a.log, "\t\tpts(n%d : %s) = %s + %s\n",
-				id, n.typ, &delta, &n.pts)
+		if a.tracer != nil {
+			a.tracer.PtsChanged(int(id), delta.AppendTo(nil))
 		}
 
 		// Apply all resolution rules attached to n.
 		for _, rule := range n.fly_solve {
-			if a.log != nil {
-				fmt.Fprintf(a.log, "\t\trule %s\n", rule)
-			}
 			rule.addflow(a, &delta)
 		}
 
+		a.collapseCycles()
 	}
-
-	if !a.nodes[0].pts.IsEmpty() {
-		panic(fmt.Sprintf("pts(0) is nonempty: %s", &a.nodes[0].pts))
-	}
-
-	// Release buffer except for final pts
-	for _, n := range a.nodes {
-		n.fly_solve = nil
-		n.flow_to.Clear()
-		n.prev_pts.Clear()
-	}
-
-	if a.log != nil {
-		fmt.Fprintf(a.log, "Solver done\n")
-
-		// Dump solution.
-		for i, n := range a.nodes {
-			if !n.pts.IsEmpty() {
-				fmt.Fprintf(a.log, "pts(n%d) = %s : %s\n", i, &n.pts, n.typ)
-			}
-		}
-	}
-
 }
 
 func (a *analysis) addWork(id nodeid) {
-	a.worklist.Insert(int(id))
-	if a.log != nil {
-		fmt.Fprintf(a.log, "\t\twork: n%d\n", id)
-	}
+	a.worklist.add(id)
 }
@@ -0,0 +1,91 @@
+package pa
+
+import (
+	"time"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Stats holds the statistics of the analysis, cheap to collect
+// unlike the log of Analyze.
+type Stats struct {
+	Nodes   int // nodes of the constraint graph
+	Objects int // nodes that start an object
+
+	// Rules counts the rules attached to nodes by kind: "load", "store",
+	// "offsetAddr", "typeFilter", "untag", "invoke", "receiver", "fp",
+	// and "intrinsic" for the rules of intrinsics and summaries.
+	Rules map[string]int
+
+	Worklist         WorklistStrategy // the order of the worklist
+	WorklistPops     int              // nodes taken from the worklist
+	Propagations     int              // copies of a delta along a flow edge
+	CollapsedNodes   int              // nodes merged into another one by cycle elimination
+	CycleSearches    int              // searches for cycles by cycle elimination
+	SubstitutedNodes int              // value nodes not created, as shared by variable substitution
+	SharedSets       int              // distinct points-to sets stored, with Options.SharedPointsToSets
+
+	Funcnodes       int                   // reachable funcnodes, i.e. clones of functions
+	ContextsPerFunc map[*ssa.Function]int // the number of clones of each reachable function
+
+	// Constraint generation is interleaved with solving: GenTime is
+	// the time spent generating, SolveTime the rest of the solver's.
+	// IntrospectionTime is that of the pre-analysis, if any.
+	GenTime           time.Duration
+	SolveTime         time.Duration
+	IntrospectionTime time.Duration
+}
+
+// ruleKind returns the key of r in Stats.Rules.
+func ruleKind(r rule) string {
+	switch r.(type) {
+	case *loadRule:
+		return "load"
+	case *storeRule:
+		return "store"
+	case *offsetAddrRule:
+		return "offsetAddr"
+	case *typeFilterRule:
+		return "typeFilter"
+	case *untagRule:
+		return "untag"
+	case *invokeRule:
+		return "invoke"
+	case *receiverRule:
+		return "receiver"
+	case *fpRule:
+		return "fp"
+	}
+	return "intrinsic"
+}
+
+// collectStats completes a.stats with the counts of the solved graph,
+// before the rules are released.
+func (a *analysis) collectStats() {
+	s := &a.stats
+	s.Nodes = len(a.nodes)
+	s.Worklist = a.opts.Worklist
+	if a.ptsTable != nil {
+		s.SharedSets = a.ptsTable.len
+	}
+	s.Rules = make(map[string]int)
+	for id, n := range a.nodes {
+		if n.obj != nil {
+			s.Objects++
+		}
+		if n.rep != nodeid(id) {
+			continue // counted with its representative
+		}
+		for _, r := range n.fly_solve {
+			s.Rules[ruleKind(r)]++
+		}
+	}
+
+	s.ContextsPerFunc = make(map[*ssa.Function]int)
+	for v, objs := range a.csfuncobj {
+		if fn, ok := v.(*ssa.Function); ok {
+			s.ContextsPerFunc[fn] = len(objs)
+			s.Funcnodes += len(objs)
+		}
+	}
+}
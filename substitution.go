@@ -0,0 +1,193 @@
+package pa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// Offline variable substitution, in the manner of hash-based value
+// numbering (HVN, Hardekopf & Lin, 2007), restricted to copy values.
+//
+// The value nodes of Phi, ChangeType, ChangeInterface, Slice,
+// SliceToArrayPointer and Extract only receive the flow of their operands:
+// nothing else can flow to them, even on the fly. So genFunc labels them
+// before creating their nodes, by the nodes their operands flow from, nil
+// aside: the copy values of a single source share its nodes, be they a
+// chain of copies or a loop of Phis entered from one value, and the copy
+// values of the same sources share one set of nodes. The nodes of the
+// other copy values are never created, and the flow to a shared node from
+// itself is dropped by addflow.
+
+// variableSubstitution reports whether pointer-equivalent copy values share nodes.
+// Provenance keeps them apart, as each records the value its facts came from.
+func (a *analysis) variableSubstitution() bool {
+	return !a.opts.NoVariableSubstitution && !a.opts.Provenance
+}
+
+// isCopyValue reports whether the value nodes of v only receive the flow
+// of its operands, all of them.
+func (a *analysis) isCopyValue(v ssa.Value) bool {
+	switch v.(type) {
+	case *ssa.Phi, *ssa.Extract:
+		return true
+	case *ssa.ChangeType, *ssa.ChangeInterface, *ssa.Slice, *ssa.SliceToArrayPointer:
+		// Their flow copies a single node, see genInstr.
+		return a.sizeof(v.Type()) == 1
+	}
+	return false
+}
+
+// copySources returns the values whose nodes flow to the copy value v,
+// with the offset of the flow within each.
+func (a *analysis) copySources(v ssa.Value) (srcs []ssa.Value, offsets []uint32) {
+	switch v := v.(type) {
+	case *ssa.Phi:
+		return v.Edges, make([]uint32, len(v.Edges))
+	case *ssa.Extract:
+		return []ssa.Value{v.Tuple}, []uint32{a.offsetOf(v.Tuple.Type(), v.Index)}
+	case *ssa.ChangeType:
+		return []ssa.Value{v.X}, []uint32{0}
+	case *ssa.ChangeInterface:
+		return []ssa.Value{v.X}, []uint32{0}
+	case *ssa.Slice:
+		return []ssa.Value{v.X}, []uint32{0}
+	case *ssa.SliceToArrayPointer:
+		return []ssa.Value{v.X}, []uint32{0}
+	}
+	return nil, nil
+}
+
+// genCopyNodes sets the value nodes of the copies of cfc, once those of
+// its other values are set. The copies are labelled by strongly connected
+// component of the copy graph, by Tarjan's algorithm: the sources of a
+// component are labelled before it.
+func (a *analysis) genCopyNodes(cfc *funcnode, copies []ssa.Value) {
+	isCopy := make(map[ssa.Value]bool, len(copies))
+	for _, v := range copies {
+		isCopy[v] = true
+	}
+	index := make(map[ssa.Value]int) // 1 + the visit order
+	low := make(map[ssa.Value]int)
+	onStack := make(map[ssa.Value]bool)
+	var stack []ssa.Value
+	labels := make(map[string]nodeid) // the nodes of each set of sources
+
+	var visit func(v ssa.Value)
+	visit = func(v ssa.Value) {
+		index[v] = len(index) + 1
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		srcs, _ := a.copySources(v)
+		for _, w := range srcs {
+			if !isCopy[w] {
+				continue
+			}
+			if index[w] == 0 {
+				visit(w)
+				if low[w] < low[v] {
+					low[v] = low[w]
+				}
+			} else if onStack[w] && index[w] < low[v] {
+				low[v] = index[w]
+			}
+		}
+		if low[v] == index[v] {
+			i := len(stack) - 1
+			for stack[i] != v {
+				i--
+			}
+			scc := stack[i:]
+			for _, w := range scc {
+				onStack[w] = false
+			}
+			a.labelCopies(cfc, scc, labels)
+			stack = stack[:i]
+		}
+	}
+	for _, v := range copies {
+		if index[v] == 0 {
+			visit(v)
+		}
+	}
+}
+
+// labelCopies sets the value nodes of the copies scc, which flow to each
+// other, from the nodes of their other sources.
+func (a *analysis) labelCopies(cfc *funcnode, scc []ssa.Value, labels map[string]nodeid) {
+	newNodes := func(v ssa.Value) nodeid {
+		var comment string
+		if a.tracer != nil {
+			comment = v.Name()
+		}
+		return a.addNodes(v.Type(), comment)
+	}
+
+	sz := a.sizeof(scc[0].Type())
+	inSCC := make(map[ssa.Value]bool, len(scc))
+	for _, v := range scc {
+		inSCC[v] = true
+		if a.sizeof(v.Type()) != sz {
+			sz = 0
+		}
+	}
+
+	// The nodes the copies flow from, sorted, without repetition.
+	var srcs []nodeid
+	seen := make(map[nodeid]bool)
+	for _, v := range scc {
+		ops, offsets := a.copySources(v)
+		for i, op := range ops {
+			if inSCC[op] {
+				continue
+			}
+			if c, ok := op.(*ssa.Const); ok && c.IsNil() {
+				continue // the nodes of nil point to nothing
+			}
+			src := a.valueNode(op)
+			if src == 0 {
+				sz = 0 // an ill-typed flow, left to addflow to report
+			}
+			if src += nodeid(offsets[i]); !seen[src] {
+				seen[src] = true
+				srcs = append(srcs, src)
+			}
+		}
+	}
+	if sz == 0 {
+		for _, v := range scc {
+			a.setValueNode(v, newNodes(v), cfc)
+		}
+		return
+	}
+	sort.Slice(srcs, func(i, j int) bool { return srcs[i] < srcs[j] })
+
+	var id nodeid
+	switch len(srcs) {
+	case 0:
+		id = newNodes(scc[0])
+	case 1:
+		id = srcs[0]
+		a.stats.SubstitutedNodes += int(sz)
+	default:
+		var key strings.Builder
+		fmt.Fprintf(&key, "%d:", sz)
+		for _, src := range srcs {
+			fmt.Fprintf(&key, "%d,", src)
+		}
+		var ok bool
+		if id, ok = labels[key.String()]; ok {
+			a.stats.SubstitutedNodes += int(sz)
+		} else {
+			id = newNodes(scc[0])
+			labels[key.String()] = id
+		}
+	}
+	for _, v := range scc {
+		a.setValueNode(v, id, cfc)
+	}
+	a.stats.SubstitutedNodes += (len(scc) - 1) * int(sz)
+}
@@ -0,0 +1,119 @@
+package pa
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// copyProgram returns a program of n functions rich in copy values:
+// Phis of loops and branches, Extracts of results, ChangeInterfaces
+// and Slices.
+func copyProgram(n int) string {
+	var b strings.Builder
+	b.WriteString(`package main
+
+type I interface{ Get() *int }
+type J interface{ I; Set(*int) }
+type T struct{ p *int; next *T }
+
+func (t *T) Get() *int  { return t.p }
+func (t *T) Set(p *int) { t.p = p }
+
+var cond bool
+var sink []I
+`)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `
+func find%[1]d(ts []*T) (*T, bool) {
+	var best *T
+	for _, t := range ts[1:] {
+		for u := t; u != nil; u = u.next {
+			if cond {
+				best = u
+			}
+		}
+	}
+	return best, best != nil
+}
+
+func use%[1]d(ts []*T, p *int) J {
+	t, ok := find%[1]d(ts)
+	var j J = t
+	for ok && cond {
+		if t, ok = find%[1]d(ts[:1]); ok {
+			j = t
+		}
+		j.Set(p)
+	}
+	var i I = j
+	sink = append(sink, i)
+	return j
+}
+`, i)
+	}
+	b.WriteString("\nfunc main() {\n\tts := []*T{{}, {next: &T{}}}\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "\tts = append(ts, &T{p: use%d(ts, new(int)).Get()})\n", i)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func TestVariableSubstitution(t *testing.T) {
+	samples := map[string]string{"copies": copyProgram(5)}
+	for name, src := range parallelSamples {
+		samples[name] = src
+	}
+	for name, src := range samples {
+		pkg := buildSample(t, src)
+		for _, k := range []int{0, 1, 2} {
+			want := solution(t, pkg, &Options{K: k, NoVariableSubstitution: true})
+			if got := solution(t, pkg, &Options{K: k}); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s, K %d: got\n%s\nwant\n%s", name, k, strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		}
+	}
+
+	pkg := buildSample(t, copyProgram(5))
+	stats := func(opts *Options) *Stats {
+		res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Stats
+	}
+	without, with := stats(&Options{K: 1, NoVariableSubstitution: true}), stats(&Options{K: 1})
+	if with.SubstitutedNodes == 0 || with.Nodes+with.SubstitutedNodes != without.Nodes {
+		t.Errorf("copies: %d nodes, %d substituted; %d nodes without substitution",
+			with.Nodes, with.SubstitutedNodes, without.Nodes)
+	}
+}
+
+func BenchmarkVariableSubstitution(b *testing.B) {
+	samples := []struct{ name, src string }{
+		{"copies", copyProgram(100)},
+		{"synthetic", syntheticProgram(60)},
+	}
+	for _, sample := range samples {
+		pkg := buildSample(b, sample.src)
+		for _, novs := range []bool{false, true} {
+			b.Run(fmt.Sprintf("%s/NoVariableSubstitution=%v", sample.name, novs), func(b *testing.B) {
+				b.ReportAllocs()
+				var stats *Stats
+				for i := 0; i < b.N; i++ {
+					res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{K: 2, NoVariableSubstitution: novs})
+					if err != nil {
+						b.Fatal(err)
+					}
+					stats = res.Stats
+				}
+				b.ReportMetric(float64(stats.Nodes), "nodes")
+				b.ReportMetric(float64(stats.Propagations), "props")
+			})
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package pa
+
+import (
+	"go/types"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// A Summary describes the effects of a function on pointer flow.
+// A summarized function is not analyzed: wherever it is called, statically
+// or dynamically, its summary is applied instead of its body.
+// The zero Summary has no effect, which stubs the function out.
+//
+// Arguments are numbered as the params of the function,
+// the receiver of a method being argument 0.
+type Summary struct {
+	Effects []Effect
+}
+
+// An Effect is one effect of a summarized function:
+// ResultAliases, Calls or FreshResult.
+type Effect interface {
+	apply(a *analysis, fc *funcnode)
+}
+
+// ResultAliases is the effect "result Result may point to whatever argument Arg
+// points to", e.g. {0, 0} for a function returning its first argument.
+// The argument must be assignable to the result, or an interface,
+// which is unwrapped into a result of concrete type.
+type ResultAliases struct {
+	Result int
+	Arg    int
+}
+
+// Calls is the effect "argument Func is called with the arguments Args",
+// e.g. {Func: 1, Args: []int{0}} for a function calling its second argument
+// with its first. Func must be a func value, and the arguments and results
+// flow as for ResultAliases.
+// Results[i], if present, is the result the i-th result of the call flows to.
+type Calls struct {
+	Func    int
+	Args    []int
+	Results []int
+}
+
+// FreshResult is the effect "result Result points to a new object",
+// allocated by the function. The result must be a pointer or a slice.
+type FreshResult struct {
+	Result int
+}
+
+// summarize returns the intrinsic applying s.
+func (s *Summary) summarize() intrinsic {
+	return func(a *analysis, fc *funcnode) {
+		for _, e := range s.Effects {
+			e.apply(a, fc)
+		}
+	}
+}
+
+// summaryOf returns the summary of fn, or of the generic function
+// fn is an instance of, if any.
+func (a *analysis) summaryOf(fn *ssa.Function) *Summary {
+	if s, ok := a.opts.Summaries[fn]; ok {
+		return s
+	}
+	if orig := fn.Origin(); orig != nil {
+		return a.opts.Summaries[orig]
+	}
+	return nil
+}
+
+func (e ResultAliases) apply(a *analysis, fc *funcnode) {
+	res, tRes := a.summaryResult(fc, e.Result)
+	arg, tArg := a.summaryArg(fc, e.Arg)
+	if tRes != nil && tArg != nil {
+		a.summaryFlow(fc, res, tRes, arg, tArg)
+	}
+}
+
+func (e Calls) apply(a *analysis, fc *funcnode) {
+	f, tFunc := a.summaryArg(fc, e.Func)
+	if tFunc == nil {
+		return
+	}
+	sig, ok := tFunc.Underlying().(*types.Signature)
+	if !ok {
+		a.unsupported(fc.fn, nil, "ill-formed summary: argument %d is not a func value", e.Func)
+		return
+	}
+
+	// Allocate a contiguous relay params/results block for the call.
+	block := a.nextNode()
+	a.addNodes(sig.Params(), "summary.params")
+	a.addNodes(sig.Results(), "summary.results")
+
+	p := block
+	for i, n := 0, sig.Params().Len(); i < n; i++ {
+		T := sig.Params().At(i).Type()
+		if i < len(e.Args) {
+			if arg, tArg := a.summaryArg(fc, e.Args[i]); tArg != nil {
+				a.summaryFlow(fc, p, T, arg, tArg)
+			}
+		}
+		p += nodeid(a.sizeof(T))
+	}
+	r := p
+	for i, n := 0, sig.Results().Len(); i < n; i++ {
+		T := sig.Results().At(i).Type()
+		if i < len(e.Results) {
+			if res, tRes := a.summaryResult(fc, e.Results[i]); tRes != nil {
+				a.summaryFlow(fc, res, tRes, r, T)
+			}
+		}
+		r += nodeid(a.sizeof(T))
+	}
+
+	a.addRule(f, &fpRule{caller: fc, params: block, value: f})
+}
+
+func (e FreshResult) apply(a *analysis, fc *funcnode) {
+	res, tRes := a.summaryResult(fc, e.Result)
+	if tRes == nil {
+		return
+	}
+	var T types.Type
+	switch t := tRes.Underlying().(type) {
+	case *types.Pointer:
+		T = t.Elem()
+	case *types.Slice:
+		T = sliceToArray(t)
+	default:
+		a.unsupported(fc.fn, nil, "ill-formed summary: result %d is not a pointer or a slice", e.Result)
+		return
+	}
+	obj := a.nextNode()
+	a.addNodes(T, "summary.fresh")
+	a.endObject(obj, fc, nil)
+	if a.nodes[res].pts.add(obj) {
+		a.addWork(res)
+	}
+}
+
+// summaryArg returns the first node and the type of argument i of fc,
+// or a nil type if it has none.
+func (a *analysis) summaryArg(fc *funcnode, i int) (nodeid, types.Type) {
+	params := summaryParams(fc.fn.Signature)
+	if i < 0 || i >= len(params) {
+		a.unsupported(fc.fn, nil, "ill-formed summary: no argument %d", i)
+		return 0, nil
+	}
+	id := a.funcParams(fc.obj)
+	for _, T := range params[:i] {
+		id += nodeid(a.sizeof(T))
+	}
+	return id, params[i]
+}
+
+// summaryParams returns the types of the arguments of a function of
+// signature sig, the receiver first.
+func summaryParams(sig *types.Signature) []types.Type {
+	var params []types.Type
+	if recv := sig.Recv(); recv != nil {
+		params = append(params, recv.Type())
+	}
+	for j := 0; j < sig.Params().Len(); j++ {
+		params = append(params, sig.Params().At(j).Type())
+	}
+	return params
+}
+
+// summaryResult returns the first node and the type of result i of fc,
+// or a nil type if it has none.
+func (a *analysis) summaryResult(fc *funcnode, i int) (nodeid, types.Type) {
+	results := fc.fn.Signature.Results()
+	if i < 0 || i >= results.Len() {
+		a.unsupported(fc.fn, nil, "ill-formed summary: no result %d", i)
+		return 0, nil
+	}
+	return a.funcResults(fc.obj) + nodeid(a.offsetOf(results, i)), results.At(i).Type()
+}
+
+// summaryFlow makes dst, of type tDst, point to whatever src, of type tSrc, points to.
+func (a *analysis) summaryFlow(fc *funcnode, dst nodeid, tDst types.Type, src nodeid, tSrc types.Type) {
+	switch {
+	case !summaryAssignable(tDst, tSrc):
+		a.unsupported(fc.fn, nil, "ill-formed summary: cannot flow %s to %s", tSrc, tDst)
+	case isInterface(tSrc) && !isInterface(tDst):
+		a.addRule(src, &untagRule{tDst, dst, false})
+	default:
+		a.addflow(dst, src, a.sizeof(tSrc), nil)
+	}
+}
+
+// summaryAssignable reports whether a summary may make a value of type tDst
+// point to whatever a value of type tSrc points to: tSrc is assignable to
+// tDst, or convertible to it without changing the layout of the objects
+// pointed to, or is an interface, whose objects of type tDst are unwrapped.
+func summaryAssignable(tDst, tSrc types.Type) bool {
+	if isInterface(tSrc) {
+		return true
+	}
+	if isInterface(tDst) {
+		return false
+	}
+	if types.AssignableTo(tSrc, tDst) || types.Identical(tSrc.Underlying(), tDst.Underlying()) {
+		return true
+	}
+	pSrc, ok1 := tSrc.Underlying().(*types.Pointer)
+	pDst, ok2 := tDst.Underlying().(*types.Pointer)
+	return ok1 && ok2 && types.Identical(pSrc.Elem().Underlying(), pDst.Elem().Underlying())
+}
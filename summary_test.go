@@ -0,0 +1,92 @@
+package pa
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+const summarySample = `package main
+
+type T struct{ a, b *int }
+type U T
+
+func alias(p *T) *T
+func conv(p *T) *U
+func bad(b []byte) *T
+func unwrap(x interface{}) *T
+func fresh() *T
+func apply(f func(*T), p *T)
+
+func use(p *T)  {}
+func useU(p *U) {}
+
+func main() {
+	x := &T{}
+	use(alias(x))
+	useU(conv(x))
+	use(bad(make([]byte, 2)))
+	use(unwrap(x))
+	use(fresh())
+	apply(use, x)
+}
+`
+
+func TestSummaries(t *testing.T) {
+	pkg := buildSample(t, summarySample)
+	fn := func(name string) *ssa.Function { return sampleFunc(t, pkg, name) }
+	for _, test := range []struct {
+		name    string
+		fn      string
+		effects []Effect
+		param   string // the param of main.use or main.useU queried
+		want    []string
+		err     string // the error, or the diagnostic with SkipUnsupported
+	}{
+		{"aliases", "main.alias", []Effect{ResultAliases{0, 0}}, "main.use", []string{"main.main:t0"}, ""},
+		{"converts", "main.conv", []Effect{ResultAliases{0, 0}}, "main.useU", []string{"main.main:t0"}, ""},
+		{"unwraps", "main.unwrap", []Effect{ResultAliases{0, 0}}, "main.use", []string{"main.main:t0"}, ""},
+		{"fresh", "main.fresh", []Effect{FreshResult{0}}, "main.use", []string{"main.fresh"}, ""},
+		{"calls", "main.apply", []Effect{Calls{Func: 0, Args: []int{1}}}, "main.use", []string{"main.main:t0"}, ""},
+		{"ill-typed", "main.bad", []Effect{ResultAliases{0, 0}}, "main.use", []string{}, "cannot flow []byte to *main.T"},
+		{"no arg", "main.fresh", []Effect{ResultAliases{0, 0}}, "main.use", []string{}, "no argument 0"},
+	} {
+		for _, skip := range []bool{false, true} {
+			opts := &Options{
+				Summaries:       map[*ssa.Function]*Summary{fn(test.fn): {Effects: test.effects}},
+				SkipUnsupported: skip,
+			}
+			res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, opts)
+			if test.err != "" && !skip {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+				continue
+			}
+			var diags []string
+			for _, d := range res.Diagnostics {
+				if d.Kind == UnsupportedConstruct {
+					diags = append(diags, d.Message)
+				}
+			}
+			if test.err != "" && (len(diags) != 1 || !strings.Contains(diags[0], test.err)) {
+				t.Errorf("%s: got diagnostics %q, want %q", test.name, diags, test.err)
+			}
+			if test.err == "" && len(diags) > 0 {
+				t.Errorf("%s: unexpected diagnostics %q", test.name, diags)
+			}
+			if test.err != "" {
+				continue // the flows of the other entries are not checked
+			}
+			if got := labels(res, sampleParam(t, pkg, test.param, "p")); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%s: %s points to %v, want %v", test.name, test.param, got, test.want)
+			}
+		}
+	}
+}
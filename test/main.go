@@ -97,13 +97,13 @@ func main() {
 		}
 	}
 
-	result, err := pa.Analyze(prog, nil, []*ssa.Package{mainPkg}, nil)
+	result, err := pa.Analyze(prog, nil, []*ssa.Package{mainPkg}, nil, nil)
 	if err != nil {
 		panic(err)
 	}
 
 	var edges []string
-	callgraph.GraphVisitEdges(result, func(edge *callgraph.Edge) error {
+	callgraph.GraphVisitEdges(result.CallGraph, func(edge *callgraph.Edge) error {
 		caller := edge.Caller.Func
 		if caller.Pkg == mainPkg {
 			edges = append(edges, fmt.Sprint(caller, " --> ", edge.Callee.Func, " line: ", prog.Fset.Position(edge.Pos()).Line))
@@ -116,7 +116,7 @@ func main() {
 		fmt.Println(edge)
 	}
 	fmt.Println()
-	visual.PrintOutput(prog, mainPkg, result, nil, true, false)
+	visual.PrintOutput(prog, mainPkg, result.CallGraph, nil, true, false)
 
 }
 
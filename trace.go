@@ -0,0 +1,149 @@
+package pa
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"io"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// A Tracer receives the events of the analysis, as they happen,
+// e.g. to record a trace and explain the results from it.
+// See Options.Tracer.
+//
+// Nodes are designated by their ids, from 0 in creation order.
+type Tracer interface {
+	// NodeCreated reports a new node of type typ;
+	// comment tells what it stands for, if known.
+	NodeCreated(id int, typ types.Type, comment string)
+
+	// RuleAdded reports a rule attached to a node, of a kind of Stats.Rules.
+	RuleAdded(id int, kind string)
+
+	// PtsChanged reports the objects added to the points-to set of a node
+	// since the solver last propagated it, when it propagates it. For a node
+	// propagated again to a new rule, delta is the whole points-to set.
+	PtsChanged(id int, delta []int)
+
+	// FuncReachable reports a new clone of fn, in context ctx.
+	FuncReachable(fn *ssa.Function, ctx Context)
+
+	// CallEdgeAdded reports a new edge of the context-sensitive call graph,
+	// or an approximate edge becoming precise. site is nil for calls from
+	// the root and from intrinsics. See Options.CHAFallback for approximate.
+	CallEdgeAdded(caller *ssa.Function, callerCtx Context, site ssa.CallInstruction,
+		callee *ssa.Function, calleeCtx Context, approximate bool)
+}
+
+// textTracer writes the events as text, for the log of Analyze.
+type textTracer struct {
+	w io.Writer
+}
+
+func (t *textTracer) NodeCreated(id int, typ types.Type, comment string) {
+	fmt.Fprintf(t.w, "\tcreate n%d %s for %s\n", id, typ, comment)
+}
+
+func (t *textTracer) RuleAdded(id int, kind string) {
+	fmt.Fprintf(t.w, "\trule %s on n%d\n", kind, id)
+}
+
+func (t *textTracer) PtsChanged(id int, delta []int) {
+	fmt.Fprintf(t.w, "\t\tpts(n%d) += %v\n", id, delta)
+}
+
+func (t *textTracer) FuncReachable(fn *ssa.Function, ctx Context) {
+	fmt.Fprintf(t.w, "\treachable %s%s\n", fn, ctx)
+}
+
+func (t *textTracer) CallEdgeAdded(caller *ssa.Function, callerCtx Context, site ssa.CallInstruction,
+	callee *ssa.Function, calleeCtx Context, approximate bool) {
+	var approx string
+	if approximate {
+		approx = " (approximate)"
+	}
+	fmt.Fprintf(t.w, "\tCallGraph: %s%s --> %s%s%s\n", caller, callerCtx, callee, calleeCtx, approx)
+}
+
+// A JSONTracer writes the events as JSON lines, one object per event, e.g.
+//
+//	{"event":"node","id":3,"type":"*int","comment":"t0"}
+//	{"event":"rule","id":3,"kind":"load"}
+//	{"event":"pts","id":3,"delta":[5,9]}
+//	{"event":"reachable","func":"main.f","context":"[t1()]"}
+//	{"event":"call","caller":"main.main","caller_context":"[]","site":"f()","pos":"x.go:10:3","callee":"main.f","callee_context":"[f()]"}
+type JSONTracer struct {
+	enc *json.Encoder
+	err error
+}
+
+// NewJSONTracer returns a JSONTracer writing to w.
+func NewJSONTracer(w io.Writer) *JSONTracer {
+	return &JSONTracer{enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error writing the events, if any.
+func (t *JSONTracer) Err() error {
+	return t.err
+}
+
+type jsonEvent struct {
+	Event         string `json:"event"`
+	ID            *int   `json:"id,omitempty"`
+	Type          string `json:"type,omitempty"`
+	Comment       string `json:"comment,omitempty"`
+	Kind          string `json:"kind,omitempty"`
+	Delta         []int  `json:"delta,omitempty"`
+	Func          string `json:"func,omitempty"`
+	Context       string `json:"context,omitempty"`
+	Caller        string `json:"caller,omitempty"`
+	CallerContext string `json:"caller_context,omitempty"`
+	Site          string `json:"site,omitempty"`
+	Pos           string `json:"pos,omitempty"`
+	Callee        string `json:"callee,omitempty"`
+	CalleeContext string `json:"callee_context,omitempty"`
+	Approximate   bool   `json:"approximate,omitempty"`
+}
+
+func (t *JSONTracer) emit(e *jsonEvent) {
+	if t.err == nil {
+		t.err = t.enc.Encode(e)
+	}
+}
+
+func (t *JSONTracer) NodeCreated(id int, typ types.Type, comment string) {
+	t.emit(&jsonEvent{Event: "node", ID: &id, Type: typ.String(), Comment: comment})
+}
+
+func (t *JSONTracer) RuleAdded(id int, kind string) {
+	t.emit(&jsonEvent{Event: "rule", ID: &id, Kind: kind})
+}
+
+func (t *JSONTracer) PtsChanged(id int, delta []int) {
+	t.emit(&jsonEvent{Event: "pts", ID: &id, Delta: delta})
+}
+
+func (t *JSONTracer) FuncReachable(fn *ssa.Function, ctx Context) {
+	t.emit(&jsonEvent{Event: "reachable", Func: fn.String(), Context: ctx.String()})
+}
+
+func (t *JSONTracer) CallEdgeAdded(caller *ssa.Function, callerCtx Context, site ssa.CallInstruction,
+	callee *ssa.Function, calleeCtx Context, approximate bool) {
+	e := &jsonEvent{
+		Event:         "call",
+		Caller:        caller.String(),
+		CallerContext: callerCtx.String(),
+		Callee:        callee.String(),
+		CalleeContext: calleeCtx.String(),
+		Approximate:   approximate,
+	}
+	if site != nil {
+		e.Site = site.String()
+		if pos := site.Pos(); pos.IsValid() {
+			e.Pos = caller.Prog.Fset.Position(pos).String()
+		}
+	}
+	t.emit(e)
+}
@@ -0,0 +1,83 @@
+package pa
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestJSONTracer(t *testing.T) {
+	pkg := buildSample(t, resultSample)
+	var buf bytes.Buffer
+	tracer := NewJSONTracer(&buf)
+	res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{K: 1, Tracer: tracer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tracer.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]int)
+	var reachable, calls []string
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e jsonEvent
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		counts[e.Event]++
+		switch e.Event {
+		case "reachable":
+			reachable = append(reachable, e.Func+e.Context)
+		case "call":
+			if e.Callee == "main.id" {
+				calls = append(calls, e.Caller+" --> "+e.Callee+e.CalleeContext)
+			}
+		}
+	}
+	if counts["node"] != res.Stats.Nodes {
+		t.Errorf("%d node events, want %d", counts["node"], res.Stats.Nodes)
+	}
+	if counts["rule"] == 0 || counts["pts"] == 0 {
+		t.Errorf("got events %v", counts)
+	}
+	sort.Strings(reachable)
+	if want := []string{"main.id[id(t0)]", "main.id[id(t1)]", "main.init[]", "main.main[]"}; !reflect.DeepEqual(reachable, want) {
+		t.Errorf("reachable %v, want %v", reachable, want)
+	}
+	sort.Strings(calls)
+	if want := []string{"main.main --> main.id[id(t0)]", "main.main --> main.id[id(t1)]"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v, want %v", calls, want)
+	}
+
+	// The text tracer of the log.
+	var log strings.Builder
+	if _, err := Analyze(pkg.Prog, &log, []*ssa.Package{pkg}, nil, &Options{K: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(log.String(), "\treachable main.id[id(t0)]\n") {
+		t.Errorf("the log does not report the clone main.id[id(t0)]:\n%s", log.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestJSONTracerError(t *testing.T) {
+	pkg := buildSample(t, resultSample)
+	tracer := NewJSONTracer(failingWriter{})
+	if _, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{Tracer: tracer}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracer.Err(); err == nil || err.Error() != "disk full" {
+		t.Errorf("Err() = %v, want disk full", err)
+	}
+}
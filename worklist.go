@@ -0,0 +1,256 @@
+package pa
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// A WorklistStrategy selects the order in which the solver takes the nodes
+// of its worklist. It does not change the results, only the work to reach them.
+type WorklistStrategy int
+
+const (
+	// WorklistIDOrder takes the least node id first.
+	WorklistIDOrder WorklistStrategy = iota
+
+	// WorklistFIFO takes the nodes in the order they were added.
+	WorklistFIFO
+
+	// WorklistLIFO takes the last node added first.
+	WorklistLIFO
+
+	// WorklistLRF takes the least recently fired node first,
+	// i.e. the one taken the longest ago, or never taken.
+	WorklistLRF
+
+	// WorklistTopological takes the node first in a topological order of
+	// the flow edges, so that a node usually receives all its flow before
+	// it is taken. The order is refreshed as the flow edges change.
+	WorklistTopological
+)
+
+var worklistStrategyNames = [...]string{
+	WorklistIDOrder:     "id order",
+	WorklistFIFO:        "FIFO",
+	WorklistLIFO:        "LIFO",
+	WorklistLRF:         "LRF",
+	WorklistTopological: "topological",
+}
+
+func (s WorklistStrategy) String() string {
+	if int(s) < len(worklistStrategyNames) {
+		return worklistStrategyNames[s]
+	}
+	return fmt.Sprintf("WorklistStrategy(%d)", int(s))
+}
+
+// A worklist holds the nodes the solver has yet to take.
+// Adding a node already in it has no effect.
+type worklist interface {
+	add(id nodeid)
+	take() (nodeid, bool) // false if empty
+}
+
+// newWorklist returns the worklist of Options.Worklist.
+func (a *analysis) newWorklist() worklist {
+	switch a.opts.Worklist {
+	case WorklistFIFO:
+		return &fifoWorklist{}
+	case WorklistLIFO:
+		return &lifoWorklist{}
+	case WorklistLRF:
+		return &lrfWorklist{}
+	case WorklistTopological:
+		return &topoWorklist{a: a}
+	}
+	return &idWorklist{}
+}
+
+type idWorklist struct {
+	nodes nodeset
+}
+
+func (w *idWorklist) add(id nodeid) { w.nodes.add(id) }
+
+func (w *idWorklist) take() (nodeid, bool) {
+	var x int
+	ok := w.nodes.TakeMin(&x)
+	return nodeid(x), ok
+}
+
+type fifoWorklist struct {
+	queued nodeset
+	queue  []nodeid
+}
+
+func (w *fifoWorklist) add(id nodeid) {
+	if w.queued.add(id) {
+		w.queue = append(w.queue, id)
+	}
+}
+
+func (w *fifoWorklist) take() (nodeid, bool) {
+	if len(w.queue) == 0 {
+		return 0, false
+	}
+	id := w.queue[0]
+	w.queue = w.queue[1:]
+	w.queued.Remove(int(id))
+	return id, true
+}
+
+type lifoWorklist struct {
+	queued nodeset
+	stack  []nodeid
+}
+
+func (w *lifoWorklist) add(id nodeid) {
+	if w.queued.add(id) {
+		w.stack = append(w.stack, id)
+	}
+}
+
+func (w *lifoWorklist) take() (nodeid, bool) {
+	if len(w.stack) == 0 {
+		return 0, false
+	}
+	id := w.stack[len(w.stack)-1]
+	w.stack = w.stack[:len(w.stack)-1]
+	w.queued.Remove(int(id))
+	return id, true
+}
+
+// lrfWorklist is a priority queue of the nodes by the time they were last taken.
+type lrfWorklist struct {
+	queued nodeset
+	queue  prioQueue
+	fired  []int // the time each node was last taken, 0 if never
+	clock  int
+}
+
+// A prioQueue is a heap of nodes by priority, then id.
+type prioQueue []prioEntry
+
+type prioEntry struct {
+	prio int
+	id   nodeid
+}
+
+func (q prioQueue) Len() int { return len(q) }
+func (q prioQueue) Less(i, j int) bool {
+	if q[i].prio != q[j].prio {
+		return q[i].prio < q[j].prio
+	}
+	return q[i].id < q[j].id
+}
+func (q prioQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *prioQueue) Push(x interface{}) { *q = append(*q, x.(prioEntry)) }
+func (q *prioQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+func (w *lrfWorklist) add(id nodeid) {
+	if !w.queued.add(id) {
+		return
+	}
+	fired := 0
+	if int(id) < len(w.fired) {
+		fired = w.fired[id]
+	}
+	heap.Push(&w.queue, prioEntry{fired, id})
+}
+
+func (w *lrfWorklist) take() (nodeid, bool) {
+	if len(w.queue) == 0 {
+		return 0, false
+	}
+	id := heap.Pop(&w.queue).(prioEntry).id
+	w.queued.Remove(int(id))
+	for int(id) >= len(w.fired) {
+		w.fired = append(w.fired, 0)
+	}
+	w.clock++
+	w.fired[id] = w.clock
+	return id, true
+}
+
+// topoWorklist takes the nodes by rounds: each round takes the nodes added
+// during the previous one, by their rank in a topological order of the flow
+// graph. The ranks are computed again when the flow graph has changed, at
+// most once per as many takes as it has nodes.
+type topoWorklist struct {
+	a       *analysis
+	next    nodeset   // the nodes added during the current round
+	round   prioQueue // the nodes left in the current round
+	rank    []int     // the rank of each node; the nodes created since are ranked last
+	version int       // a.flowVersion when ranked
+	takes   int       // the takes left before ranking again
+}
+
+func (w *topoWorklist) add(id nodeid) { w.next.add(id) }
+
+func (w *topoWorklist) take() (nodeid, bool) {
+	if len(w.round) == 0 {
+		if w.next.IsEmpty() {
+			return 0, false
+		}
+		if w.takes <= 0 && w.version != w.a.flowVersion {
+			w.rerank()
+		}
+		for _, x := range w.next.AppendTo(w.a.deltaSpace) {
+			w.round = append(w.round, prioEntry{w.rankOf(nodeid(x)), nodeid(x)})
+		}
+		heap.Init(&w.round)
+		w.next.Clear()
+	}
+	w.takes--
+	return heap.Pop(&w.round).(prioEntry).id, true
+}
+
+func (w *topoWorklist) rankOf(id nodeid) int {
+	if int(id) < len(w.rank) {
+		return w.rank[id]
+	}
+	return len(w.rank)
+}
+
+// rerank ranks the nodes by the reverse postorder of a depth-first search
+// of the flow graph of representatives, a topological order but for the
+// cycles not collapsed yet.
+func (w *topoWorklist) rerank() {
+	a := w.a
+	rank := w.rank[:0]
+	for range a.nodes {
+		rank = append(rank, -1) // not visited
+	}
+	next := len(rank)
+	var succs []int // the successors of the nodes being visited, as in findSCCs
+	var visit func(v nodeid)
+	visit = func(v nodeid) {
+		rank[v] = -2 // being visited
+		i := len(succs)
+		succs = a.nodes[v].flow_to.AppendTo(succs)
+		for _, y := range succs[i:len(succs):len(succs)] {
+			if u := a.nodes[y].rep; rank[u] == -1 {
+				visit(u)
+			}
+		}
+		succs = succs[:i]
+		next--
+		rank[v] = next
+	}
+	for _, n := range a.nodes {
+		if v := n.rep; rank[v] == -1 {
+			visit(v)
+		}
+	}
+	for id, n := range a.nodes {
+		rank[id] = rank[n.rep]
+	}
+	w.rank = rank
+	w.version = a.flowVersion
+	w.takes = len(rank)
+}
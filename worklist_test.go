@@ -0,0 +1,33 @@
+package pa
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestWorklistStrategies(t *testing.T) {
+	strategies := []WorklistStrategy{WorklistIDOrder, WorklistFIFO, WorklistLIFO, WorklistLRF, WorklistTopological}
+	for name, src := range parallelSamples {
+		pkg := buildSample(t, src)
+		want := solution(t, pkg, &Options{K: 1})
+		for _, w := range strategies {
+			if got := solution(t, pkg, &Options{K: 1, Worklist: w}); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s, %s: got\n%s\nwant\n%s", name, w, strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		}
+	}
+
+	pkg := buildSample(t, parallelSamples["synthetic"])
+	for _, w := range strategies {
+		res, err := Analyze(pkg.Prog, nil, []*ssa.Package{pkg}, nil, &Options{K: 1, Worklist: w})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Stats.Worklist != w || res.Stats.WorklistPops == 0 {
+			t.Errorf("%s: Stats.Worklist %s, %d pops", w, res.Stats.Worklist, res.Stats.WorklistPops)
+		}
+	}
+}